@@ -21,6 +21,7 @@ func main() {
 	cmdRoot := GetRootCommand()
 	cmdRoot.AddCommand(
 		cmdbuild.CommandBuild(),
+		cmdbuild.CommandPlugin(),
 	)
 
 	_ = cmdRoot.Execute()
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	cmdbuild "github.com/smzgl/proto-gen-doc/internal/build"
+)
+
+// protoc-gen-gendoc is the protoc / buf plugin of proto-gen-doc
+// protoc --gendoc_out=../doc -I ../proto ../proto/api/*.proto
+func main() {
+	err := cmdbuild.RunPlugin(os.Stdin, os.Stdout)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "protoc-gen-gendoc: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -10,11 +10,56 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Input modes of the build command.
+const (
+	InputAuto       = "auto"
+	InputJSON       = "json"
+	InputProto      = "proto"
+	InputDescriptor = "descriptor"
+)
+
+// descriptorSetSuffixes are the file suffixes of serialized FileDescriptorSet files.
+var descriptorSetSuffixes = []string{".pb", ".binpb", ".protoset"}
+
+// Config holds the settings of a build.
+type Config struct {
+	Target      string
+	Output      string
+	Input       string
+	ImportPaths []string
+	LegacyJSON  bool
+	JSONOrder   string
+	// Format is the output format, FormatMarkdown by default
+	Format string
+	// Lang is the language of the headings, DefaultLang by default
+	Lang string
+	// TemplatesDir holds templates overriding the embedded ones of the same name
+	TemplatesDir string
+	// TypesFile is a json file of examples of common types, see Template.LoadTypes
+	TypesFile string
+	// JSONSchema writes a JSON Schema file per message next to the docs
+	JSONSchema bool
+	// OpenAPI writes openapi.yaml of the google.api.http bindings
+	OpenAPI bool
+	// Strict fails the build if any error is diagnosed
+	Strict bool
+	// DiagnosticsFile is written with the json report of the diagnostics
+	DiagnosticsFile string
+	// ExamplesDir holds user supplied examples, see Template.LoadExamples
+	ExamplesDir string
+}
+
 // CommandBuild is used to compile proto files
 // proto-gen-doc build -o ../doc ../proto
+// proto-gen-doc build -o ../doc -I ../proto -I ../third_party ../proto
 func CommandBuild() *cobra.Command {
 	var clean bool
-	var output string
+	cfg := Config{
+		Input:     InputAuto,
+		JSONOrder: OrderDeclaration,
+		Format:    FormatMarkdown,
+		Lang:      DefaultLang,
+	}
 
 	cmd := &cobra.Command{
 		Use:   "build",
@@ -22,12 +67,14 @@ func CommandBuild() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			if clean {
-				err := os.RemoveAll(output)
+				err := os.RemoveAll(cfg.Output)
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "remove output dir failure, output dir: %s, err: %+v\n", output, err)
-				}	
+					fmt.Fprintf(os.Stderr, "remove output dir failure, output dir: %s, err: %+v\n", cfg.Output, err)
+				}
 			}
-			_, err := ExecuteCommand(args[0], output)
+
+			cfg.Target = args[0]
+			_, err := ExecuteCommand(&cfg)
 			if err != nil {
 				_, _ = fmt.Fprintf(os.Stderr, "execute %s args: %v error: %v\n", cmd.Name(), args, err)
 				os.Exit(1)
@@ -39,43 +86,163 @@ func CommandBuild() *cobra.Command {
 
 	flags := cmd.PersistentFlags()
 	flags.BoolVarP(&clean, "clean", "c", clean, "clean output dir")
-	flags.StringVarP(&output, "output", "o", output, "output dir")
+	flags.StringVarP(&cfg.Output, "output", "o", cfg.Output, "output dir")
+	flags.StringVar(&cfg.Input, "input", cfg.Input, "input type: auto, json (protoc-gen-doc *.proto.json), proto (*.proto sources) or descriptor (FileDescriptorSet files)")
+	flags.BoolVar(&cfg.LegacyJSON, "legacy-json", cfg.LegacyJSON, "use proto field names and type names in json examples instead of the proto3 JSON mapping")
+	flags.StringVar(&cfg.JSONOrder, "json-order", cfg.JSONOrder, "key order of json examples: declaration, number (field number) or name (alphabetical)")
+	flags.StringVar(&cfg.Format, "format", cfg.Format, "output format: markdown (proto.md files) or html (self-contained site of index.html files)")
+	flags.StringVar(&cfg.Lang, "lang", cfg.Lang, "language of the docs: en or zh, other languages may be added as locales/<lang>.json of the templates dir")
+	flags.StringVar(&cfg.TemplatesDir, "templates", cfg.TemplatesDir, "dir of templates overriding the embedded ones of the same name, e.g. proto.doc.md.tmpl, and of partials overriding single blocks, e.g. _rows.md.tmpl or _rows.html.tmpl defining \"field-row\"")
+	flags.StringVar(&cfg.TypesFile, "types", cfg.TypesFile, "json file mapping full type names to their json example, e.g. common types of other repos")
+	flags.BoolVar(&cfg.JSONSchema, "json-schema", cfg.JSONSchema, "write a JSON Schema (draft 2020-12) file per message to <package dir>/schema")
+	flags.BoolVar(&cfg.OpenAPI, "openapi", cfg.OpenAPI, "write openapi.yaml (OpenAPI 3.1) of the methods with google.api.http bindings")
+	flags.StringVar(&cfg.ExamplesDir, "examples", cfg.ExamplesDir, "dir of json examples named by the full name of messages, e.g. api.v1.User.json, or methods, e.g. api.v1.UserService.GetUser.request.json")
+	flags.BoolVar(&cfg.Strict, "strict", cfg.Strict, "fail the build if any error is diagnosed, e.g. unknown types or invalid examples")
+	flags.StringVar(&cfg.DiagnosticsFile, "diagnostics", cfg.DiagnosticsFile, "write the diagnostics as json report to this file")
+	flags.StringArrayVarP(&cfg.ImportPaths, "proto_path", "I", cfg.ImportPaths, "import path of proto sources, defaults to the target dir")
 	return cmd
 }
 
-func ExecuteCommand(target, output string) (string, error) {
-	var err error
-
-	target, err = filepath.Abs(target)
+// ExecuteCommand builds the doc of cfg.Target into cfg.Output
+func ExecuteCommand(cfg *Config) (string, error) {
+	target, err := filepath.Abs(cfg.Target)
 	if err != nil {
 		return "", fmt.Errorf("failed to abs target path: %w", err)
 	}
 
-	output, err = filepath.Abs(output)
+	output, err := filepath.Abs(cfg.Output)
 	if err != nil {
 		return "", fmt.Errorf("failed to abs output path: %w", err)
 	}
 
-	var files []string
+	err = checkJSONOrder(cfg.JSONOrder)
+	if err != nil {
+		return "", err
+	}
 
-	// 遍历目录, 合并json文件
-	err = filepath.Walk(target, func(path string, info fs.FileInfo, err error) error {
-		if strings.HasSuffix(path, ".proto.json") {
-			files = append(files, path)
+	err = checkFormat(cfg.Format)
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.TemplatesDir != "" {
+		err = checkTemplatesDir(cfg.TemplatesDir)
+		if err != nil {
+			return "", err
 		}
+	}
 
-		return err
-	})
+	err = checkLang(cfg.TemplatesDir, cfg.Lang)
+	if err != nil {
+		return "", err
+	}
 
-	var tmpl Template
+	tmpl := Template{
+		LegacyJSON: cfg.LegacyJSON,
+		JSONOrder:  cfg.JSONOrder,
+	}
 
-	err = tmpl.ParseFiles(files...)
-	if err != nil {
-		return "", fmt.Errorf("parse files failure: %w\n", err)
+	if cfg.TypesFile != "" {
+		err = tmpl.LoadTypes(cfg.TypesFile)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.ExamplesDir != "" {
+		err = tmpl.LoadExamples(cfg.ExamplesDir)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	input := cfg.Input
+	if input == "" || input == InputAuto {
+		input, err = detectInput(target)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch input {
+	case InputJSON:
+		files, err := walkFiles(target, ".proto.json")
+		if err != nil {
+			return "", err
+		}
+
+		err = tmpl.ParseFiles(files...)
+		if err != nil {
+			return "", fmt.Errorf("parse files failure: %w\n", err)
+		}
+
+	case InputProto:
+		files, err := walkFiles(target, ".proto")
+		if err != nil {
+			return "", err
+		}
+
+		importPaths := []string{target}
+		if len(cfg.ImportPaths) > 0 {
+			importPaths = importPaths[:0]
+			for _, importPath := range cfg.ImportPaths {
+				importPath, err = filepath.Abs(importPath)
+				if err != nil {
+					return "", fmt.Errorf("failed to abs import path: %w", err)
+				}
+
+				importPaths = append(importPaths, importPath)
+			}
+		}
+
+		err = tmpl.ParseProtoFiles(importPaths, files...)
+		if err != nil {
+			return "", fmt.Errorf("parse proto files failure: %w\n", err)
+		}
+
+	case InputDescriptor:
+		files, err := walkFiles(target, descriptorSetSuffixes...)
+		if err != nil {
+			return "", err
+		}
+
+		err = tmpl.ParseDescriptorSets(files...)
+		if err != nil {
+			return "", fmt.Errorf("parse descriptor sets failure: %w\n", err)
+		}
+
+	default:
+		return "", fmt.Errorf("unknown input type: %s", input)
+	}
+
+	if cfg.OpenAPI {
+		// the OpenAPI document reports the bindings it leaves out
+		tmpl.OpenAPI()
+	}
+
+	tmpl.WriteDiagnostics(os.Stderr)
+
+	if cfg.DiagnosticsFile != "" {
+		err = writeDiagnosticsReport(&tmpl, cfg.DiagnosticsFile)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.Strict {
+		err = strictError(&tmpl)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	renderer := &Renderer{
-		tmpl: &tmpl,
+		tmpl:      &tmpl,
+		format:    cfg.Format,
+		templates: cfg.TemplatesDir,
+		lang:      cfg.Lang,
+		schema:    cfg.JSONSchema,
+		openAPI:   cfg.OpenAPI,
 	}
 
 	err = renderer.Render(output)
@@ -85,3 +252,63 @@ func ExecuteCommand(target, output string) (string, error) {
 
 	return "success!", err
 }
+
+// detectInput treats a single file as descriptor set, in a dir it prefers protoc-gen-doc
+// json files and falls back to proto sources.
+func detectInput(target string) (string, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", fmt.Errorf("stat target failure, target: %s, err: %w", target, err)
+	}
+
+	if !info.IsDir() {
+		return InputDescriptor, nil
+	}
+
+	files, err := walkFiles(target, ".proto.json")
+	if err != nil {
+		return "", err
+	}
+
+	if len(files) > 0 {
+		return InputJSON, nil
+	}
+
+	return InputProto, nil
+}
+
+// walkFiles returns all files under dir having one of the suffixes, a file passed as dir is
+// returned whatever its suffix.
+func walkFiles(dir string, suffixes ...string) ([]string, error) {
+	var files []string
+
+	// 遍历目录
+	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		if path == dir {
+			files = append(files, path)
+			return nil
+		}
+
+		for _, suffix := range suffixes {
+			if strings.HasSuffix(path, suffix) {
+				files = append(files, path)
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk dir failure, dir: %s, err: %w", dir, err)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,263 @@
+package build
+
+import (
+	"sort"
+	"strings"
+)
+
+// refKey is the key of the marker replacing a message in json examples when the message
+// already appears on the way from the root, e.g. {"$ref": "pkg.TreeNode"}.
+const refKey = "$ref"
+
+// Cycle is a cycle of messages referencing each other through their fields, the first
+// message has the smallest full name and the cycle returns to it after the last one.
+type Cycle struct {
+	Messages []*Message
+}
+
+// String formats the cycle, e.g. "pkg.Tree → pkg.Node → pkg.Tree".
+func (c Cycle) String() string {
+	names := make([]string, 0, len(c.Messages)+1)
+	for _, message := range c.Messages {
+		names = append(names, message.FullName)
+	}
+
+	if len(c.Messages) > 0 {
+		names = append(names, c.Messages[0].FullName)
+	}
+
+	return strings.Join(names, " → ")
+}
+
+// findCycles reports every elementary cycle of the message graph into tmpl.Cycles.
+func (tmpl *Template) findCycles() {
+	var nodes []*Message
+
+	index := make(map[string]int)
+	for _, file := range tmpl.Files {
+		for _, message := range file.Messages {
+			if message.Ismapentry {
+				continue
+			}
+
+			index[message.FullName] = -1
+			nodes = append(nodes, message)
+		}
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].FullName < nodes[j].FullName
+	})
+
+	for i, node := range nodes {
+		index[node.FullName] = i
+	}
+
+	// edges are the distinct message types of the fields, map fields point to the value type
+	edges := make([][]int, len(nodes))
+	for i, node := range nodes {
+		seen := make(map[int]bool)
+		for _, field := range node.Fields {
+			if j, ok := index[field.FullType]; ok && !seen[j] {
+				seen[j] = true
+				edges[i] = append(edges[i], j)
+			}
+		}
+	}
+
+	tmpl.Cycles = nil
+	tmpl.recursive = make(map[string]bool)
+
+	// cycles never leave a strongly connected component, the others are skipped
+	component := components(edges)
+	size := make(map[int]int)
+	for _, c := range component {
+		size[c]++
+	}
+
+	onCycle := func(i int) bool {
+		if size[component[i]] > 1 {
+			return true
+		}
+
+		for _, j := range edges[i] {
+			if j == i {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	// each cycle is found once, starting from its smallest node and visiting larger ones only,
+	// nodes are blocked as long as they cannot get back to the start (Johnson's algorithm)
+	blocked := make([]bool, len(nodes))
+	blockedBy := make([]map[int]bool, len(nodes))
+	var path []int
+
+	var unblock func(i int)
+	unblock = func(i int) {
+		blocked[i] = false
+		for j := range blockedBy[i] {
+			delete(blockedBy[i], j)
+			if blocked[j] {
+				unblock(j)
+			}
+		}
+	}
+
+	var walk func(start, i int) bool
+	walk = func(start, i int) bool {
+		found := false
+
+		path = append(path, i)
+		blocked[i] = true
+
+		for _, j := range edges[i] {
+			switch {
+			case j == start:
+				cycle := &Cycle{}
+				for _, k := range path {
+					cycle.Messages = append(cycle.Messages, nodes[k])
+				}
+				tmpl.Cycles = append(tmpl.Cycles, cycle)
+				found = true
+			case j > start && component[j] == component[start] && !blocked[j]:
+				if walk(start, j) {
+					found = true
+				}
+			}
+		}
+
+		if found {
+			unblock(i)
+		} else {
+			for _, j := range edges[i] {
+				if j > start && component[j] == component[start] {
+					blockedBy[j][i] = true
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		return found
+	}
+
+	for start, node := range nodes {
+		if !onCycle(start) {
+			continue
+		}
+
+		tmpl.recursive[node.FullName] = true
+
+		for i := start; i < len(nodes); i++ {
+			if component[i] == component[start] {
+				blocked[i] = false
+				blockedBy[i] = make(map[int]bool)
+			}
+		}
+
+		walk(start, start)
+	}
+}
+
+// components returns the strongly connected component of each node of the graph (Tarjan's algorithm).
+func components(edges [][]int) []int {
+	component := make([]int, len(edges))
+	index := make([]int, len(edges))
+	low := make([]int, len(edges))
+	onStack := make([]bool, len(edges))
+
+	var stack []int
+	var next, count int
+
+	for i := range index {
+		index[i] = -1
+	}
+
+	var connect func(i int)
+	connect = func(i int) {
+		index[i] = next
+		low[i] = next
+		next++
+
+		stack = append(stack, i)
+		onStack[i] = true
+
+		for _, j := range edges[i] {
+			switch {
+			case index[j] < 0:
+				connect(j)
+				if low[j] < low[i] {
+					low[i] = low[j]
+				}
+			case onStack[j] && index[j] < low[i]:
+				low[i] = index[j]
+			}
+		}
+
+		if low[i] != index[i] {
+			return
+		}
+
+		for {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[j] = false
+			component[j] = count
+
+			if j == i {
+				break
+			}
+		}
+
+		count++
+	}
+
+	for i := range edges {
+		if index[i] < 0 {
+			connect(i)
+		}
+	}
+
+	return component
+}
+
+// collectRefs returns the sorted full names of the messages referenced by $ref markers in obj.
+func collectRefs(obj interface{}) []string {
+	refs := make(map[string]bool)
+
+	var walk func(obj interface{})
+	walk = func(obj interface{}) {
+		switch x := obj.(type) {
+		case []interface{}:
+			for _, v := range x {
+				walk(v)
+			}
+		case map[string]interface{}:
+			if ref, ok := x[refKey].(string); ok && len(x) == 1 {
+				refs[ref] = true
+				return
+			}
+
+			for _, v := range x {
+				walk(v)
+			}
+		case *OrderedObject:
+			for _, k := range x.Keys() {
+				v, _ := x.Get(k)
+				walk(v)
+			}
+		}
+	}
+
+	walk(obj)
+
+	res := make([]string, 0, len(refs))
+	for ref := range refs {
+		res = append(res, ref)
+	}
+
+	sort.Strings(res)
+	return res
+}
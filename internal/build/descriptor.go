@@ -0,0 +1,597 @@
+package build
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ParseDescriptors converts linked file descriptors into the template model. Only the given
+// files are documented, their imports are used to resolve types and custom options.
+func (tmpl *Template) ParseDescriptors(files ...protoreflect.FileDescriptor) error {
+	parser := newDescriptorParser(files)
+
+	for _, fd := range files {
+		err := tmpl.appendFile(parser.parseFile(fd))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, scalar := range scalarValues {
+		tmpl.appendScalarValue(scalar)
+	}
+
+	tmpl.finishParse()
+	tmpl.buildMessagesJsonString()
+	return nil
+}
+
+// descriptorParser builds the template model from protoreflect descriptors.
+type descriptorParser struct {
+	types *protoregistry.Types
+	// descriptor.proto as imported by the parsed files, custom options extend its messages
+	descriptorFile protoreflect.FileDescriptor
+}
+
+func newDescriptorParser(files []protoreflect.FileDescriptor) *descriptorParser {
+	p := &descriptorParser{
+		types: new(protoregistry.Types),
+	}
+
+	visited := make(map[string]bool)
+	for _, fd := range files {
+		p.registerExtensions(fd, visited)
+	}
+
+	return p
+}
+
+// registerExtensions registers all extensions of fd and its imports, so that custom options
+// stored as unknown fields can be decoded.
+func (p *descriptorParser) registerExtensions(fd protoreflect.FileDescriptor, visited map[string]bool) {
+	if visited[fd.Path()] {
+		return
+	}
+
+	visited[fd.Path()] = true
+
+	if fd.Path() == "google/protobuf/descriptor.proto" {
+		p.descriptorFile = fd
+	}
+
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		p.registerExtensions(imports.Get(i).FileDescriptor, visited)
+	}
+
+	var register func(extensions protoreflect.ExtensionDescriptors)
+	register = func(extensions protoreflect.ExtensionDescriptors) {
+		for i := 0; i < extensions.Len(); i++ {
+			_ = p.types.RegisterExtension(dynamicpb.NewExtensionType(extensions.Get(i)))
+		}
+	}
+
+	register(fd.Extensions())
+	walkMessages(fd.Messages(), func(md protoreflect.MessageDescriptor) {
+		register(md.Extensions())
+	})
+}
+
+func (p *descriptorParser) parseFile(fd protoreflect.FileDescriptor) *File {
+	file := &File{
+		Name:        fd.Path(),
+		Description: p.fileDescription(fd),
+		Package:     string(fd.Package()),
+		Options:     p.parseOptions(fd.Options()),
+	}
+
+	enums := fd.Enums()
+	for i := 0; i < enums.Len(); i++ {
+		file.Enums = append(file.Enums, p.parseEnum(enums.Get(i)))
+	}
+
+	extensions := fd.Extensions()
+	for i := 0; i < extensions.Len(); i++ {
+		file.Extensions = append(file.Extensions, p.parseExtension(extensions.Get(i)))
+	}
+
+	walkMessages(fd.Messages(), func(md protoreflect.MessageDescriptor) {
+		file.Messages = append(file.Messages, p.parseMessage(md))
+
+		enums := md.Enums()
+		for i := 0; i < enums.Len(); i++ {
+			file.Enums = append(file.Enums, p.parseEnum(enums.Get(i)))
+		}
+	})
+
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		file.Services = append(file.Services, p.parseService(services.Get(i)))
+	}
+
+	file.HasEnums = len(file.Enums) > 0
+	file.HasExtensions = len(file.Extensions) > 0
+	file.HasMessages = len(file.Messages) > 0
+	file.HasServices = len(file.Services) > 0
+	return file
+}
+
+func (p *descriptorParser) parseMessage(md protoreflect.MessageDescriptor) *Message {
+	message := &Message{
+		Name:        string(md.Name()),
+		LongName:    longName(md),
+		FullName:    string(md.FullName()),
+		Description: p.description(md),
+		Options:     p.parseOptions(md.Options()),
+	}
+
+	extensions := md.Extensions()
+	for i := 0; i < extensions.Len(); i++ {
+		message.Extensions = append(message.Extensions, &MessageExtension{
+			FileExtension: *p.parseExtension(extensions.Get(i)),
+			ScopeType:     string(md.Name()),
+			ScopeLongType: longName(md),
+			ScopeFullType: string(md.FullName()),
+		})
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		message.Fields = append(message.Fields, p.parseField(fields.Get(i)))
+	}
+
+	ranges := md.ReservedRanges()
+	for i := 0; i < ranges.Len(); i++ {
+		// field ranges are half-open
+		message.ReservedRanges = append(message.ReservedRanges, &ReservedRange{
+			Start: int(ranges.Get(i)[0]),
+			End:   int(ranges.Get(i)[1]) - 1,
+		})
+	}
+
+	message.ReservedNames = reservedNames(md.ReservedNames())
+
+	oneofs := md.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		od := oneofs.Get(i)
+		if od.IsSynthetic() {
+			continue
+		}
+
+		if options := p.parseOptions(od.Options()); options != nil {
+			if message.OneofOptions == nil {
+				message.OneofOptions = make(map[string]Options)
+			}
+			message.OneofOptions[string(od.Name())] = options
+		}
+	}
+
+	message.HasExtensions = len(message.Extensions) > 0
+	message.HasFields = len(message.Fields) > 0
+	message.HasOneofs = md.Oneofs().Len() > 0
+	return message
+}
+
+func (p *descriptorParser) parseField(fd protoreflect.FieldDescriptor) *MessageField {
+	field := &MessageField{
+		Name:         string(fd.Name()),
+		Number:       int(fd.Number()),
+		JSONName:     fd.JSONName(),
+		Description:  p.description(fd),
+		Label:        fieldLabel(fd),
+		Ismap:        fd.IsMap(),
+		DefaultValue: defaultValue(fd),
+		Options:      p.parseOptions(fd.Options()),
+	}
+
+	field.Type, field.LongType, field.FullType = fieldType(fd)
+
+	if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+		field.Isoneof = true
+		field.Oneofdecl = string(oneof.Name())
+	}
+
+	return field
+}
+
+func (p *descriptorParser) parseExtension(xd protoreflect.ExtensionDescriptor) *FileExtension {
+	extension := &FileExtension{
+		Name:               string(xd.Name()),
+		LongName:           longName(xd),
+		FullName:           string(xd.FullName()),
+		Description:        p.description(xd),
+		Label:              fieldLabel(xd),
+		Number:             int(xd.Number()),
+		DefaultValue:       defaultValue(xd),
+		ContainingType:     string(xd.ContainingMessage().Name()),
+		ContainingLongType: longName(xd.ContainingMessage()),
+		ContainingFullType: string(xd.ContainingMessage().FullName()),
+	}
+
+	extension.Type, extension.LongType, extension.FullType = fieldType(xd)
+	return extension
+}
+
+func (p *descriptorParser) parseEnum(ed protoreflect.EnumDescriptor) *Enum {
+	enum := &Enum{
+		Name:        string(ed.Name()),
+		LongName:    longName(ed),
+		FullName:    string(ed.FullName()),
+		Description: p.description(ed),
+		Options:     p.parseOptions(ed.Options()),
+	}
+
+	values := ed.Values()
+	for i := 0; i < values.Len(); i++ {
+		value := values.Get(i)
+		enum.Values = append(enum.Values, &EnumValue{
+			Name:        string(value.Name()),
+			Number:      strconv.Itoa(int(value.Number())),
+			Description: p.description(value),
+			Options:     p.parseOptions(value.Options()),
+		})
+	}
+
+	ranges := ed.ReservedRanges()
+	for i := 0; i < ranges.Len(); i++ {
+		enum.ReservedRanges = append(enum.ReservedRanges, &ReservedRange{
+			Start: int(ranges.Get(i)[0]),
+			End:   int(ranges.Get(i)[1]),
+			Enum:  true,
+		})
+	}
+
+	enum.ReservedNames = reservedNames(ed.ReservedNames())
+	return enum
+}
+
+func (p *descriptorParser) parseService(sd protoreflect.ServiceDescriptor) *Service {
+	service := &Service{
+		Name:        string(sd.Name()),
+		LongName:    longName(sd),
+		FullName:    string(sd.FullName()),
+		Description: p.description(sd),
+		Options:     p.parseOptions(sd.Options()),
+	}
+
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		service.Methods = append(service.Methods, &ServiceMethod{
+			Name:              string(md.Name()),
+			Description:       p.description(md),
+			RequestType:       string(md.Input().Name()),
+			RequestLongType:   longName(md.Input()),
+			RequestFullType:   string(md.Input().FullName()),
+			RequestStreaming:  md.IsStreamingClient(),
+			ResponseType:      string(md.Output().Name()),
+			ResponseLongType:  longName(md.Output()),
+			ResponseFullType:  string(md.Output().FullName()),
+			ResponseStreaming: md.IsStreamingServer(),
+			Options:           p.parseOptions(md.Options()),
+		})
+	}
+
+	return service
+}
+
+// parseOptions decodes the options message of a descriptor. Custom options are carried as
+// unknown fields, so the message is decoded again with the registered extensions.
+func (p *descriptorParser) parseOptions(options proto.Message) Options {
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return nil
+	}
+
+	bs, err := proto.Marshal(options)
+	if err != nil || len(bs) == 0 {
+		return nil
+	}
+
+	md := options.ProtoReflect().Descriptor()
+	if p.descriptorFile != nil {
+		if d := p.descriptorFile.Messages().ByName(md.Name()); d != nil {
+			md = d
+		}
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	err = proto.UnmarshalOptions{Resolver: p.types}.Unmarshal(bs, msg)
+	if err != nil {
+		return nil
+	}
+
+	res := make(Options)
+
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		name := optionName(fd)
+
+		switch name {
+		case "validate.rules", "buf.validate.field", "buf.validate.message", "buf.validate.oneof":
+			res[name] = &ValidatorExtension{
+				rules: flattenRules("", v.Message()),
+			}
+		default:
+			res[name] = optionValue(fd, v)
+		}
+		return true
+	})
+
+	if len(res) == 0 {
+		return nil
+	}
+
+	return res
+}
+
+// flattenRules turns a validate.FieldRules or buf.validate.FieldRules message into a list of
+// rules named by their field path, e.g. "string.min_len".
+func flattenRules(prefix string, msg protoreflect.Message) []ValidatorRule {
+	var rules []ValidatorRule
+
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !msg.Has(fd) {
+			continue
+		}
+
+		name := string(fd.Name())
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		v := msg.Get(fd)
+
+		switch {
+		case fd.IsList():
+			rules = append(rules, ValidatorRule{Name: name, Value: optionValue(fd, v)})
+
+		case fd.Message() != nil && !isWellKnownTime(fd.Message()):
+			nested := flattenRules(name, v.Message())
+			if len(nested) == 0 {
+				// empty rule messages, e.g. `{message: {}}`, still carry meaning
+				nested = append(nested, ValidatorRule{Name: name, Value: true})
+			}
+			rules = append(rules, nested...)
+
+		default:
+			rules = append(rules, ValidatorRule{Name: name, Value: fieldValue(fd, v)})
+		}
+	}
+
+	return rules
+}
+
+// optionName returns the field name of standard options and the full name of extensions.
+func optionName(fd protoreflect.FieldDescriptor) string {
+	if fd.IsExtension() {
+		return string(fd.FullName())
+	}
+
+	return string(fd.Name())
+}
+
+// optionValue converts a protoreflect value, including lists and maps, into a plain go value.
+func optionValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		values := make([]interface{}, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			values = append(values, fieldValue(fd, list.Get(i)))
+		}
+		return values
+
+	case fd.IsMap():
+		values := make(map[string]interface{})
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			values[k.String()] = fieldValue(fd.MapValue(), v)
+			return true
+		})
+		return values
+
+	default:
+		return fieldValue(fd, v)
+	}
+}
+
+// fieldValue converts a singular protoreflect value into a plain go value, messages become
+// maps keyed by field name.
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if value := fd.Enum().Values().ByNumber(v.Enum()); value != nil {
+			return string(value.Name())
+		}
+		return int32(v.Enum())
+
+	case protoreflect.BytesKind:
+		return string(v.Bytes())
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg := v.Message()
+		switch msg.Descriptor().FullName() {
+		case "google.protobuf.Duration":
+			return formatDuration(msg.Get(msg.Descriptor().Fields().ByName("seconds")).Int(),
+				msg.Get(msg.Descriptor().Fields().ByName("nanos")).Int())
+		case "google.protobuf.Timestamp":
+			return formatTimestamp(msg.Get(msg.Descriptor().Fields().ByName("seconds")).Int(),
+				msg.Get(msg.Descriptor().Fields().ByName("nanos")).Int())
+		}
+
+		res := make(map[string]interface{})
+		msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+			res[optionName(fd)] = optionValue(fd, v)
+			return true
+		})
+		return res
+
+	default:
+		return v.Interface()
+	}
+}
+
+func isWellKnownTime(md protoreflect.MessageDescriptor) bool {
+	return md.FullName() == "google.protobuf.Duration" || md.FullName() == "google.protobuf.Timestamp"
+}
+
+// formatDuration formats a duration the way the proto3 JSON mapping does, e.g. "1.5s".
+func formatDuration(seconds, nanos int64) string {
+	sign := ""
+	if seconds < 0 || nanos < 0 {
+		sign, seconds, nanos = "-", -seconds, -nanos
+	}
+
+	s := strconv.FormatInt(seconds, 10)
+	if nanos != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%09d", nanos), "0")
+	}
+
+	return sign + s + "s"
+}
+
+// formatTimestamp formats a timestamp as RFC 3339 in UTC.
+func formatTimestamp(seconds, nanos int64) string {
+	return time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano)
+}
+
+func (p *descriptorParser) fileDescription(fd protoreflect.FileDescriptor) string {
+	const (
+		syntaxPath  = 12
+		packagePath = 2
+	)
+
+	for _, path := range []int32{syntaxPath, packagePath} {
+		loc := fd.SourceLocations().ByPath(protoreflect.SourcePath{path})
+		if desc := description(loc); desc != "" {
+			return desc
+		}
+	}
+
+	return ""
+}
+
+func (p *descriptorParser) description(d protoreflect.Descriptor) string {
+	return description(d.ParentFile().SourceLocations().ByDescriptor(d))
+}
+
+// description returns the leading comments of a location, or the trailing ones if there are
+// no leading comments. Comments starting with @exclude are dropped.
+func description(loc protoreflect.SourceLocation) string {
+	comments := loc.LeadingComments
+	if strings.TrimSpace(comments) == "" {
+		comments = loc.TrailingComments
+	}
+
+	lines := strings.Split(comments, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimRight(line, " \t\r"), " ")
+	}
+
+	desc := strings.TrimSpace(strings.Join(lines, "\n"))
+	if strings.HasPrefix(desc, "@exclude") {
+		return ""
+	}
+
+	return desc
+}
+
+func reservedNames(names protoreflect.Names) []string {
+	var res []string
+	for i := 0; i < names.Len(); i++ {
+		res = append(res, string(names.Get(i)))
+	}
+
+	return res
+}
+
+// walkMessages calls fn for all messages, including nested messages, in declaration order.
+func walkMessages(messages protoreflect.MessageDescriptors, fn func(md protoreflect.MessageDescriptor)) {
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		fn(md)
+		walkMessages(md.Messages(), fn)
+	}
+}
+
+// longName returns the full name of a descriptor without its package.
+func longName(d protoreflect.Descriptor) string {
+	pkg := string(d.ParentFile().Package())
+	if pkg == "" {
+		return string(d.FullName())
+	}
+
+	return strings.TrimPrefix(string(d.FullName()), pkg+".")
+}
+
+func fieldLabel(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.Cardinality() == protoreflect.Repeated:
+		return "repeated"
+	case fd.Cardinality() == protoreflect.Required:
+		return "required"
+	case fd.Syntax() == protoreflect.Proto3 && !fd.HasOptionalKeyword():
+		return ""
+	default:
+		return "optional"
+	}
+}
+
+// fieldType returns the short, long and full type name of a field.
+func fieldType(fd protoreflect.FieldDescriptor) (string, string, string) {
+	var d protoreflect.Descriptor
+
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		d = fd.Enum()
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		d = fd.Message()
+	default:
+		kind := fd.Kind().String()
+		return kind, kind, kind
+	}
+
+	return string(d.Name()), longName(d), string(d.FullName())
+}
+
+func defaultValue(fd protoreflect.FieldDescriptor) string {
+	if !fd.HasDefault() {
+		return ""
+	}
+
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		return string(fd.DefaultEnumValue().Name())
+	case protoreflect.BytesKind:
+		return string(fd.Default().Bytes())
+	default:
+		return fmt.Sprint(fd.Default().Interface())
+	}
+}
+
+// scalarValues lists the protobuf scalar value types, see
+// https://developers.google.com/protocol-buffers/docs/proto3#scalar
+var scalarValues = []*ScalarValue{
+	{ProtoType: "double", CppType: "double", CSharp: "double", GoType: "float64", JavaType: "double", PhpType: "float", PythonType: "float", RubyType: "Float"},
+	{ProtoType: "float", CppType: "float", CSharp: "float", GoType: "float32", JavaType: "float", PhpType: "float", PythonType: "float", RubyType: "Float"},
+	{ProtoType: "int32", Notes: "Uses variable-length encoding. Inefficient for encoding negative numbers – if your field is likely to have negative values, use sint32 instead.", CppType: "int32", CSharp: "int", GoType: "int32", JavaType: "int", PhpType: "integer", PythonType: "int", RubyType: "Bignum or Fixnum (as required)"},
+	{ProtoType: "int64", Notes: "Uses variable-length encoding. Inefficient for encoding negative numbers – if your field is likely to have negative values, use sint64 instead.", CppType: "int64", CSharp: "long", GoType: "int64", JavaType: "long", PhpType: "integer/string", PythonType: "int/long", RubyType: "Bignum"},
+	{ProtoType: "uint32", Notes: "Uses variable-length encoding.", CppType: "uint32", CSharp: "uint", GoType: "uint32", JavaType: "int", PhpType: "integer", PythonType: "int/long", RubyType: "Bignum or Fixnum (as required)"},
+	{ProtoType: "uint64", Notes: "Uses variable-length encoding.", CppType: "uint64", CSharp: "ulong", GoType: "uint64", JavaType: "long", PhpType: "integer/string", PythonType: "int/long", RubyType: "Bignum or Fixnum (as required)"},
+	{ProtoType: "sint32", Notes: "Uses variable-length encoding. Signed int value. These more efficiently encode negative numbers than regular int32s.", CppType: "int32", CSharp: "int", GoType: "int32", JavaType: "int", PhpType: "integer", PythonType: "int", RubyType: "Bignum or Fixnum (as required)"},
+	{ProtoType: "sint64", Notes: "Uses variable-length encoding. Signed int value. These more efficiently encode negative numbers than regular int64s.", CppType: "int64", CSharp: "long", GoType: "int64", JavaType: "long", PhpType: "integer/string", PythonType: "int/long", RubyType: "Bignum"},
+	{ProtoType: "fixed32", Notes: "Always four bytes. More efficient than uint32 if values are often greater than 2^28.", CppType: "uint32", CSharp: "uint", GoType: "uint32", JavaType: "int", PhpType: "integer", PythonType: "int", RubyType: "Bignum or Fixnum (as required)"},
+	{ProtoType: "fixed64", Notes: "Always eight bytes. More efficient than uint64 if values are often greater than 2^56.", CppType: "uint64", CSharp: "ulong", GoType: "uint64", JavaType: "long", PhpType: "integer/string", PythonType: "int/long", RubyType: "Bignum"},
+	{ProtoType: "sfixed32", Notes: "Always four bytes.", CppType: "int32", CSharp: "int", GoType: "int32", JavaType: "int", PhpType: "integer", PythonType: "int", RubyType: "Bignum or Fixnum (as required)"},
+	{ProtoType: "sfixed64", Notes: "Always eight bytes.", CppType: "int64", CSharp: "long", GoType: "int64", JavaType: "long", PhpType: "integer/string", PythonType: "int/long", RubyType: "Bignum"},
+	{ProtoType: "bool", CppType: "bool", CSharp: "bool", GoType: "bool", JavaType: "boolean", PhpType: "boolean", PythonType: "boolean", RubyType: "TrueClass/FalseClass"},
+	{ProtoType: "string", Notes: "A string must always contain UTF-8 encoded or 7-bit ASCII text.", CppType: "string", CSharp: "string", GoType: "string", JavaType: "String", PhpType: "string", PythonType: "str/unicode", RubyType: "String (UTF-8)"},
+	{ProtoType: "bytes", Notes: "May contain any arbitrary sequence of bytes.", CppType: "string", CSharp: "ByteString", GoType: "[]byte", JavaType: "ByteString", PhpType: "string", PythonType: "str", RubyType: "String (ASCII-8BIT)"},
+}
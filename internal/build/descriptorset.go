@@ -0,0 +1,99 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// thirdPartyPrefixes lists imports which are never documented when they are part of a
+// descriptor set, e.g. produced with --include_imports.
+var thirdPartyPrefixes = []string{
+	"google/protobuf/",
+	"google/api/",
+	"validate/",
+	"buf/validate/",
+}
+
+// ParseDescriptorSets reads serialized google.protobuf.FileDescriptorSet files and converts
+// them into the template model. Build them with --include_imports and --include_source_info
+// to resolve custom options and keep comments.
+func (tmpl *Template) ParseDescriptorSets(filenames ...string) error {
+	var protos []*descriptorpb.FileDescriptorProto
+
+	seen := make(map[string]bool)
+
+	for _, filename := range filenames {
+		bs, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("read file failure, path: %s, err: %w", filename, err)
+		}
+
+		var set descriptorpb.FileDescriptorSet
+		err = proto.Unmarshal(bs, &set)
+		if err != nil {
+			return fmt.Errorf("decode descriptor set failure, path: %s, err: %w", filename, err)
+		}
+
+		for _, fdp := range set.File {
+			if seen[fdp.GetName()] {
+				continue
+			}
+
+			seen[fdp.GetName()] = true
+			protos = append(protos, fdp)
+		}
+	}
+
+	var names []string
+	for _, fdp := range protos {
+		if !isThirdPartyFile(fdp.GetName()) {
+			names = append(names, fdp.GetName())
+		}
+	}
+
+	files, err := linkFileDescriptors(protos, names)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.ParseDescriptors(files...)
+}
+
+// linkFileDescriptors links descriptor protos and returns the descriptors of the named files.
+// Imports missing from protos are tolerated, their types are left unresolved.
+func linkFileDescriptors(protos []*descriptorpb.FileDescriptorProto, names []string) ([]protoreflect.FileDescriptor, error) {
+	registry, err := protodesc.FileOptions{AllowUnresolvable: true}.NewFiles(&descriptorpb.FileDescriptorSet{
+		File: protos,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("link file descriptors failure: %w", err)
+	}
+
+	files := make([]protoreflect.FileDescriptor, 0, len(names))
+	for _, name := range names {
+		fd, err := registry.FindFileByPath(name)
+		if err != nil {
+			return nil, fmt.Errorf("find file descriptor failure, name: %s, err: %w", name, err)
+		}
+
+		files = append(files, fd)
+	}
+
+	return files, nil
+}
+
+func isThirdPartyFile(name string) bool {
+	for _, prefix := range thirdPartyPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,120 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Severities of diagnostics.
+const (
+	// SeverityError marks problems which make the docs wrong or incomplete, they fail strict builds
+	SeverityError = "error"
+	// SeverityWarning marks problems the docs do not suffer from
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem found while building the docs.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	// File is the proto file the element is declared in, if known
+	File string `json:"file,omitempty"`
+	// Element is the full name of the message, field, method or example concerned
+	Element string `json:"element,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic, e.g. "error: api/user.proto: api.User: unknown object: Foo".
+func (d Diagnostic) String() string {
+	parts := []string{d.Severity}
+	if d.File != "" {
+		parts = append(parts, d.File)
+	}
+	if d.Element != "" {
+		parts = append(parts, d.Element)
+	}
+
+	return strings.Join(append(parts, d.Message), ": ")
+}
+
+// DiagnosticsReport is the machine readable report of a build.
+type DiagnosticsReport struct {
+	Errors      int           `json:"errors"`
+	Warnings    int           `json:"warnings"`
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+}
+
+func (tmpl *Template) diagnose(severity, filename, element, format string, args ...interface{}) {
+	d := &Diagnostic{
+		Severity: severity,
+		File:     filename,
+		Element:  element,
+		Message:  fmt.Sprintf(format, args...),
+	}
+
+	tmpl.Diagnostics = append(tmpl.Diagnostics, d)
+}
+
+func (tmpl *Template) errorf(filename, element, format string, args ...interface{}) {
+	tmpl.diagnose(SeverityError, filename, element, format, args...)
+}
+
+func (tmpl *Template) warnf(filename, element, format string, args ...interface{}) {
+	tmpl.diagnose(SeverityWarning, filename, element, format, args...)
+}
+
+// Report summarizes the diagnostics.
+func (tmpl *Template) Report() *DiagnosticsReport {
+	report := &DiagnosticsReport{Diagnostics: make([]*Diagnostic, 0, len(tmpl.Diagnostics))}
+
+	for _, d := range tmpl.Diagnostics {
+		switch d.Severity {
+		case SeverityError:
+			report.Errors++
+		case SeverityWarning:
+			report.Warnings++
+		}
+
+		report.Diagnostics = append(report.Diagnostics, d)
+	}
+
+	return report
+}
+
+// WriteDiagnostics prints the diagnostics, one per line.
+func (tmpl *Template) WriteDiagnostics(w io.Writer) {
+	for _, d := range tmpl.Diagnostics {
+		_, _ = fmt.Fprintln(w, d)
+	}
+}
+
+// JSON encodes the report.
+func (r *DiagnosticsReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// writeDiagnosticsReport writes the report of tmpl to filename.
+func writeDiagnosticsReport(tmpl *Template, filename string) error {
+	bs, err := tmpl.Report().JSON()
+	if err != nil {
+		return fmt.Errorf("encode diagnostics failure: %w", err)
+	}
+
+	err = os.WriteFile(filename, append(bs, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("write diagnostics failure, path: %s, err: %w", filename, err)
+	}
+
+	return nil
+}
+
+// strictError fails strict builds having errors.
+func strictError(tmpl *Template) error {
+	if n := tmpl.Report().Errors; n > 0 {
+		return fmt.Errorf("strict mode: %d error(s) found", n)
+	}
+
+	return nil
+}
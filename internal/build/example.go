@@ -0,0 +1,453 @@
+package build
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// sampleTime is the instant used for timestamps in examples.
+var sampleTime = time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+// wrapperKinds maps the wrapper types to the rule type validating them.
+var wrapperKinds = map[string]string{
+	"google.protobuf.BoolValue":   "bool",
+	"google.protobuf.Int32Value":  "int32",
+	"google.protobuf.Int64Value":  "int64",
+	"google.protobuf.UInt32Value": "uint32",
+	"google.protobuf.UInt64Value": "uint64",
+	"google.protobuf.FloatValue":  "float",
+	"google.protobuf.DoubleValue": "double",
+	"google.protobuf.StringValue": "string",
+	"google.protobuf.BytesValue":  "bytes",
+	"google.protobuf.Duration":    "duration",
+	"google.protobuf.Timestamp":   "timestamp",
+}
+
+// ruleGroups returns the validate rules of a field below prefix, e.g. "repeated.items.",
+// grouped by rule type.
+func (f MessageField) ruleGroups(prefix string) map[string]ruleGroup {
+	validator := f.Options.Validator()
+	if validator == nil {
+		return nil
+	}
+
+	var rules []ValidatorRule
+	for _, rule := range validator.Rules() {
+		if strings.HasPrefix(rule.Name, prefix) {
+			rules = append(rules, ValidatorRule{Name: strings.TrimPrefix(rule.Name, prefix), Value: rule.Value})
+		}
+	}
+
+	_, groups := groupRules(rules)
+	return groups
+}
+
+// sampleField adjusts the example value v of a field, or of its items, map keys or map values
+// selected by prefix, so that it satisfies the validate rules of the field.
+func (tmpl *Template) sampleField(objects map[string]Object, f *MessageField, prefix, fullType string, v interface{}) interface{} {
+	if tmpl.LegacyJSON {
+		return v
+	}
+
+	groups := f.ruleGroups(prefix)
+	if len(groups) == 0 {
+		return v
+	}
+
+	if enum := objects[fullType].Enum; enum != nil {
+		if g, ok := groups["enum"]; ok {
+			return g.sampleEnum(enum, v)
+		}
+		return v
+	}
+
+	kind := fullType
+	if k, ok := wrapperKinds[fullType]; ok {
+		kind = k
+	}
+
+	g, ok := groups[kind]
+	if !ok {
+		return v
+	}
+
+	switch kind {
+	case "string":
+		return g.sampleString(stringValue(v))
+	case "bool":
+		if c, ok := g["const"].(bool); ok {
+			return c
+		}
+		return v
+	case "bytes":
+		return v
+	case "timestamp":
+		return g.sampleTimestamp(v)
+	case "duration":
+		seconds := g.sampleNumber(1, false)
+		whole := int64(seconds)
+		return formatDuration(whole, int64((seconds-float64(whole))*1e9))
+	default:
+		return g.sampleScalar(kind, v)
+	}
+}
+
+// sampleList repeats the example of an item as often as the rules require, but at most 3 times.
+func (tmpl *Template) sampleList(objects map[string]Object, f *MessageField, v interface{}) []interface{} {
+	item := tmpl.sampleField(objects, f, "repeated.items.", f.FullType, v)
+
+	count := 1
+	if !tmpl.LegacyJSON {
+		if min, ok := toFloat(f.ruleGroups("")["repeated"]["min_items"]); ok && min > 1 {
+			count = int(min)
+		}
+
+		if count > 3 {
+			count = 3
+		}
+	}
+
+	res := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		res = append(res, item)
+	}
+
+	return res
+}
+
+func (g ruleGroup) sampleEnum(enum *Enum, v interface{}) interface{} {
+	name := func(number interface{}) interface{} {
+		for _, value := range enum.Values {
+			if value.Number == formatRuleValue(number) {
+				return value.Name
+			}
+		}
+		return v
+	}
+
+	if c, ok := g["const"]; ok {
+		return name(c)
+	}
+
+	if in, ok := g["in"].([]interface{}); ok && len(in) > 0 {
+		return name(in[0])
+	}
+
+	if notIn, ok := g["not_in"].([]interface{}); ok {
+		excluded := make(map[string]bool)
+		for _, number := range notIn {
+			excluded[formatRuleValue(number)] = true
+		}
+
+		for _, value := range enum.Values {
+			if !excluded[value.Number] {
+				return value.Name
+			}
+		}
+	}
+
+	return v
+}
+
+func (g ruleGroup) sampleString(v string) interface{} {
+	if c, ok := g["const"]; ok {
+		return formatRuleValue(c)
+	}
+
+	if in, ok := g["in"].([]interface{}); ok && len(in) > 0 {
+		return formatRuleValue(in[0])
+	}
+
+	for _, format := range ruleFormats {
+		if g.bool(format.name) {
+			return format.sample
+		}
+	}
+
+	min, hasMin := toFloat(g["min_len"])
+	max, hasMax := toFloat(g["max_len"])
+	if n, ok := toFloat(g["len"]); ok {
+		min, hasMin = n, true
+		max, hasMax = n, true
+	}
+
+	if pattern, ok := g["pattern"]; ok {
+		maxLen := -1
+		if hasMax {
+			maxLen = int(max)
+		}
+
+		if sample, err := regexSample(formatRuleValue(pattern), int(min), maxLen); err == nil {
+			return sample
+		}
+	}
+
+	prefix := ""
+	if x, ok := g["prefix"]; ok {
+		prefix = formatRuleValue(x)
+	}
+
+	contains := ""
+	if x, ok := g["contains"]; ok && !strings.Contains(prefix+v, formatRuleValue(x)) {
+		contains = formatRuleValue(x)
+	}
+
+	suffix := ""
+	if x, ok := g["suffix"]; ok {
+		suffix = formatRuleValue(x)
+	}
+
+	// the sample is padded or cut, the prefix, contains and suffix are kept
+	padding := ""
+	n := utf8.RuneCountInString(prefix + v + contains + suffix)
+	if hasMin && n < int(min) {
+		padding = strings.Repeat("x", int(min)-n)
+	}
+
+	if hasMax && n > int(max) {
+		runes := []rune(v)
+		cut := n - int(max)
+		if cut > len(runes) {
+			cut = len(runes)
+		}
+		v = string(runes[:len(runes)-cut])
+	}
+
+	return prefix + v + contains + padding + suffix
+}
+
+func (g ruleGroup) sampleTimestamp(v interface{}) interface{} {
+	if c, ok := g["const"]; ok {
+		return formatRuleValue(c)
+	}
+
+	for _, name := range []string{"gte", "gt", "lte", "lt"} {
+		if x, ok := g[name].(string); ok {
+			return x
+		}
+	}
+
+	if g.bool("gt_now") {
+		return sampleTime.AddDate(100, 0, 0).Format(time.RFC3339)
+	}
+
+	return v
+}
+
+// sampleScalar adjusts a number to the rules and formats it as the proto3 JSON mapping does.
+func (g ruleGroup) sampleScalar(kind string, v interface{}) interface{} {
+	def, ok := toFloat(v)
+	if !ok {
+		return v
+	}
+
+	switch kind {
+	case "float", "double":
+		return g.sampleNumber(def, false)
+	case "int32", "sint32", "sfixed32", "uint32", "fixed32":
+		return int64(g.sampleNumber(def, true))
+	case "int64", "sint64", "sfixed64":
+		return strconv.FormatInt(int64(g.sampleNumber(def, true)), 10)
+	case "uint64", "fixed64":
+		return strconv.FormatUint(uint64(g.sampleNumber(def, true)), 10)
+	default:
+		return v
+	}
+}
+
+// sampleNumber returns def, or the closest value to it satisfying const, in, gt, gte, lt and lte.
+func (g ruleGroup) sampleNumber(def float64, integer bool) float64 {
+	if c, ok := toFloat(g["const"]); ok {
+		return c
+	}
+
+	if in, ok := g["in"].([]interface{}); ok && len(in) > 0 {
+		if v, ok := toFloat(in[0]); ok {
+			return v
+		}
+	}
+
+	lo, hasLo := toFloat(g["gte"])
+	loExclusive := false
+	if gt, ok := toFloat(g["gt"]); ok {
+		lo, hasLo, loExclusive = gt, true, true
+	}
+
+	hi, hasHi := toFloat(g["lte"])
+	hiExclusive := false
+	if lt, ok := toFloat(g["lt"]); ok {
+		hi, hasHi, hiExclusive = lt, true, true
+	}
+
+	step := 1.0
+	if !integer && hasLo && hasHi && hi > lo {
+		step = (hi - lo) / 2
+	}
+
+	v := def
+	if hasLo && (v < lo || (v == lo && loExclusive)) {
+		v = lo
+		if loExclusive {
+			v += step
+		}
+	}
+
+	if hasHi && (v > hi || (v == hi && hiExclusive)) {
+		v = hi
+		if hiExclusive {
+			v -= step
+		}
+	}
+
+	return v
+}
+
+// toFloat converts numeric rule values, and durations formatted like "1.5s", into a float.
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	case int:
+		return float64(x), true
+	case string:
+		if d, err := time.ParseDuration(x); err == nil {
+			return d.Seconds(), true
+		}
+		if f, err := strconv.ParseFloat(x, 64); err == nil {
+			return f, true
+		}
+		return 0, false
+	default:
+		return 0, false
+	}
+}
+
+// regexSample returns a short string matching the pattern of at least minLen runes, and at most
+// maxLen unless it is negative. The repetitions of the pattern are expanded to reach minLen.
+func regexSample(pattern string, minLen, maxLen int) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("parse pattern failure, pattern: %s, err: %w", pattern, err)
+	}
+
+	re = re.Simplify()
+
+	var sb strings.Builder
+	(&regexSampler{sb: &sb}).write(re)
+
+	// the shortest sample is written again, its repetitions adding the missing runes
+	if n := utf8.RuneCountInString(sb.String()); n < minLen {
+		sb.Reset()
+		(&regexSampler{sb: &sb, pad: minLen - n}).write(re)
+	}
+
+	sample := sb.String()
+	if n := utf8.RuneCountInString(sample); n < minLen || (maxLen >= 0 && n > maxLen) {
+		return "", fmt.Errorf("sample of pattern does not fit the length, pattern: %s, sample: %s", pattern, sample)
+	}
+
+	return sample, nil
+}
+
+// regexSampler writes the shortest sample of a regexp, its repetitions are written pad more runes.
+type regexSampler struct {
+	sb  *strings.Builder
+	pad int
+	// extra is set while a repetition is written beyond the shortest sample
+	extra bool
+}
+
+func (s *regexSampler) write(re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		s.writeString(string(re.Rune))
+	case syntax.OpCharClass:
+		s.writeString(string(charClassSample(re.Rune)))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		s.writeString("a")
+	case syntax.OpCapture, syntax.OpAlternate:
+		s.write(re.Sub[0])
+	case syntax.OpPlus:
+		s.write(re.Sub[0])
+		s.repeat(re.Sub[0], -1)
+	case syntax.OpStar:
+		s.repeat(re.Sub[0], -1)
+	case syntax.OpQuest:
+		s.repeat(re.Sub[0], 1)
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			s.write(re.Sub[0])
+		}
+		if re.Max < 0 {
+			s.repeat(re.Sub[0], -1)
+		} else {
+			s.repeat(re.Sub[0], re.Max-re.Min)
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			s.write(sub)
+		}
+	}
+}
+
+// repeat writes re up to max more times, unbounded if max is negative, while padding is needed.
+func (s *regexSampler) repeat(re *syntax.Regexp, max int) {
+	extra := s.extra
+	s.extra = true
+	defer func() { s.extra = extra }()
+
+	for i := 0; (max < 0 || i < max) && s.pad > 0; i++ {
+		pad := s.pad
+		s.write(re)
+		if s.pad == pad {
+			// re matches the empty string only
+			return
+		}
+	}
+}
+
+func (s *regexSampler) writeString(str string) {
+	s.sb.WriteString(str)
+	if s.extra {
+		s.pad -= utf8.RuneCountInString(str)
+	}
+}
+
+// charClassSample picks a readable rune of a character class given as lo-hi pairs.
+func charClassSample(ranges []rune) rune {
+	for _, r := range []rune{'a', 'A', '0', '_', '-'} {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= r && r <= ranges[i+1] {
+				return r
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for r := ranges[i]; r <= ranges[i+1]; r++ {
+			if r > ' ' && r < utf8.RuneSelf {
+				return r
+			}
+		}
+	}
+
+	if len(ranges) > 0 {
+		return ranges[0]
+	}
+
+	return 'a'
+}
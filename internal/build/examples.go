@@ -0,0 +1,349 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// exampleTag sets the example of a field in its comment, e.g. "@example 42" or
+// `@example {"city": "Berlin"}`, values which are no valid json are taken as string.
+const exampleTag = "@example"
+
+// fencedJSONPattern matches the fenced json blocks of message and method comments, the info
+// string of method examples may name the side, e.g. "```json request".
+var fencedJSONPattern = regexp.MustCompile("(?s)```json[ \\t]*(request|response)?[ \\t]*\\r?\\n(.*?)```")
+
+// LoadExamples registers the examples of a dir, the files are named by the full name of the
+// message, e.g. "api.user.v1.User.json", or of the method followed by the side, e.g.
+// "api.user.v1.UserService.GetUser.request.json". They override examples in comments and
+// must be loaded before parsing.
+func (tmpl *Template) LoadExamples(dir string) error {
+	files, err := walkFiles(dir, ".json")
+	if err != nil {
+		return err
+	}
+
+	if tmpl.Examples == nil {
+		tmpl.Examples = make(map[string]interface{})
+	}
+
+	for _, filename := range files {
+		bs, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("read example failure, path: %s, err: %w", filename, err)
+		}
+
+		example, err := decodeOrderedJSON(bs)
+		if err != nil {
+			return fmt.Errorf("decode example failure, path: %s, err: %w", filename, err)
+		}
+
+		tmpl.Examples[strings.TrimSuffix(filepath.Base(filename), ".json")] = example
+	}
+
+	return nil
+}
+
+// parseExamples moves the examples of comments out of the descriptions and applies the
+// examples loaded from files.
+func (tmpl *Template) parseExamples() {
+	used := make(map[string]bool)
+
+	loaded := func(name string) interface{} {
+		example, ok := tmpl.Examples[name]
+		if ok {
+			used[name] = true
+		}
+		return example
+	}
+
+	for _, file := range tmpl.Files {
+		for _, message := range file.Messages {
+			var blocks []fencedJSON
+			message.Description, blocks = extractFencedJSON(message.Description)
+			if len(blocks) > 0 {
+				message.Example = tmpl.decodeCommentExample(message.Filename, message.FullName, blocks[0].body)
+			}
+
+			if example := loaded(message.FullName); example != nil {
+				message.Example = example
+			}
+
+			for _, field := range message.Fields {
+				var value string
+				field.Description, value = extractExampleTag(field.Description)
+				if value == "" {
+					continue
+				}
+
+				example, err := decodeOrderedJSON([]byte(value))
+				if err != nil {
+					example = value
+				}
+
+				field.Example = example
+			}
+		}
+
+		for _, service := range file.Services {
+			for _, method := range service.Methods {
+				name := service.FullName + "." + method.Name
+
+				var blocks []fencedJSON
+				method.Description, blocks = extractFencedJSON(method.Description)
+				for i, block := range blocks {
+					switch {
+					case block.side == "request" || (block.side == "" && i == 0):
+						method.RequestExample = tmpl.decodeCommentExample(service.Filename, name, block.body)
+					case block.side == "response" || (block.side == "" && i == 1):
+						method.ResponseExample = tmpl.decodeCommentExample(service.Filename, name, block.body)
+					}
+				}
+
+				if example := loaded(name + ".request"); example != nil {
+					method.RequestExample = example
+				}
+
+				if example := loaded(name + ".response"); example != nil {
+					method.ResponseExample = example
+				}
+			}
+		}
+	}
+
+	for name := range tmpl.Examples {
+		if !used[name] {
+			tmpl.warnf("", name, "example of unknown message or method")
+		}
+	}
+}
+
+type fencedJSON struct {
+	side string
+	body string
+}
+
+// extractFencedJSON removes the fenced json blocks from a description.
+func extractFencedJSON(desc string) (string, []fencedJSON) {
+	var blocks []fencedJSON
+	for _, match := range fencedJSONPattern.FindAllStringSubmatch(desc, -1) {
+		blocks = append(blocks, fencedJSON{side: match[1], body: match[2]})
+	}
+
+	if len(blocks) == 0 {
+		return desc, nil
+	}
+
+	return strings.TrimSpace(fencedJSONPattern.ReplaceAllString(desc, "")), blocks
+}
+
+// extractExampleTag removes the @example line from a description and returns its value.
+func extractExampleTag(desc string) (string, string) {
+	var value string
+	var lines []string
+
+	for _, line := range strings.Split(desc, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, exampleTag) {
+			value = strings.TrimSpace(strings.TrimPrefix(trimmed, exampleTag))
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")), value
+}
+
+func (tmpl *Template) decodeCommentExample(filename, name, body string) interface{} {
+	example, err := decodeOrderedJSON([]byte(body))
+	if err != nil {
+		tmpl.errorf(filename, name, "decode example in comment failure: %v", err)
+		return nil
+	}
+
+	return example
+}
+
+// checkExamples reports the user supplied examples which do not match the fields anymore.
+func (tmpl *Template) checkExamples(objects map[string]Object) {
+	for _, file := range tmpl.Files {
+		report := func(filename, name string, problems []string) {
+			for _, problem := range problems {
+				tmpl.errorf(filename, name, "invalid example, %s", problem)
+			}
+		}
+
+		for _, message := range file.Messages {
+			if message.Example != nil {
+				report(message.Filename, message.FullName, checkMessageExample(objects, message, "$", message.Example))
+			}
+
+			for _, field := range message.Fields {
+				if field.Example != nil {
+					report(message.Filename, message.FullName+"."+field.Name, checkFieldExample(objects, field, "$", field.Example))
+				}
+			}
+		}
+
+		for _, service := range file.Services {
+			for _, method := range service.Methods {
+				name := service.FullName + "." + method.Name
+
+				if method.RequestExample != nil {
+					report(service.Filename, name+" request", checkValueExample(objects, method.RequestFullType, "$", method.RequestExample))
+				}
+
+				if method.ResponseExample != nil {
+					report(service.Filename, name+" response", checkValueExample(objects, method.ResponseFullType, "$", method.ResponseExample))
+				}
+			}
+		}
+	}
+}
+
+func checkMessageExample(objects map[string]Object, message *Message, path string, v interface{}) []string {
+	if !isJSONObject(v) {
+		return []string{fmt.Sprintf("%s: expect object of %s", path, message.FullName)}
+	}
+
+	var problems []string
+
+	oneofs := make(map[string]string)
+	for _, key := range objectKeys(v) {
+		f := message.field(key)
+		if f == nil {
+			problems = append(problems, fmt.Sprintf("%s: unknown field %s of %s", path, key, message.FullName))
+			continue
+		}
+
+		if f.Isoneof {
+			if other, ok := oneofs[f.Oneofdecl]; ok {
+				problems = append(problems, fmt.Sprintf("%s: %s and %s of oneof %s are both set", path, other, key, f.Oneofdecl))
+			}
+			oneofs[f.Oneofdecl] = key
+		}
+
+		problems = append(problems, checkFieldExample(objects, f, path+"."+key, objectValue(v, key))...)
+	}
+
+	return problems
+}
+
+func checkFieldExample(objects map[string]Object, f *MessageField, path string, v interface{}) []string {
+	switch {
+	case v == nil:
+		return nil
+
+	case f.Isarray:
+		list, ok := v.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expect array", path)}
+		}
+
+		var problems []string
+		for i, item := range list {
+			problems = append(problems, checkValueExample(objects, f.FullType, fmt.Sprintf("%s[%d]", path, i), item)...)
+		}
+		return problems
+
+	case f.Ismap:
+		if !isJSONObject(v) {
+			return []string{fmt.Sprintf("%s: expect object", path)}
+		}
+
+		var problems []string
+		for _, key := range objectKeys(v) {
+			problems = append(problems, checkValueExample(objects, f.FullType, path+"."+key, objectValue(v, key))...)
+		}
+		return problems
+
+	default:
+		return checkValueExample(objects, f.FullType, path, v)
+	}
+}
+
+// checkValueExample checks v against a type, well-known and registered types are not checked.
+func checkValueExample(objects map[string]Object, fullType, path string, v interface{}) []string {
+	obj := objects[fullType]
+
+	switch {
+	case v == nil:
+		return nil
+
+	case obj.Message != nil:
+		return checkMessageExample(objects, obj.Message, path, v)
+
+	case obj.Enum != nil:
+		for _, value := range obj.Enum.Values {
+			if value.Name == fmt.Sprint(v) || value.Number == fmt.Sprint(v) {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: %v is no value of %s", path, v, obj.Enum.FullName)}
+
+	case obj.ScalarValue != nil:
+		if !scalarExampleValid(obj.ScalarValue.ProtoType, v) {
+			return []string{fmt.Sprintf("%s: %v is no %s", path, v, obj.ScalarValue.ProtoType)}
+		}
+		return nil
+
+	default:
+		return nil
+	}
+}
+
+// scalarExampleValid reports whether v is a json value of the scalar type, numbers may be
+// given as strings as the proto3 JSON mapping allows.
+func scalarExampleValid(protoType string, v interface{}) bool {
+	switch protoType {
+	case "bool":
+		_, ok := v.(bool)
+		return ok
+	case "string", "bytes":
+		_, ok := v.(string)
+		return ok
+	}
+
+	switch x := v.(type) {
+	case json.Number, float64:
+		return true
+	case string:
+		if protoType == "float" || protoType == "double" {
+			switch x {
+			case "NaN", "Infinity", "-Infinity":
+				return true
+			}
+		}
+
+		_, err := strconv.ParseFloat(x, 64)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+func isJSONObject(v interface{}) bool {
+	switch v.(type) {
+	case *OrderedObject, map[string]interface{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// RequestJSON formats the example of the request.
+func (m ServiceMethod) RequestJSON() string {
+	bs, _ := json.MarshalIndent(m.RequestExample, "", "  ")
+	return string(bs)
+}
+
+// ResponseJSON formats the example of the response.
+func (m ServiceMethod) ResponseJSON() string {
+	bs, _ := json.MarshalIndent(m.ResponseExample, "", "  ")
+	return string(bs)
+}
@@ -3,6 +3,7 @@ package build
 import (
 	"fmt"
 	"html/template"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
@@ -14,6 +15,7 @@ var funcMap = map[string]interface{}{
 	"anchor": AnchorFilter,
 	"raw":    RawFilter,
 	"inc":    IncFilter,
+	"root":   RootFilter,
 }
 
 var (
@@ -33,6 +35,16 @@ func IncFilter(content int) template.HTML {
 	return template.HTML(fmt.Sprintf("%d", content+1))
 }
 
+// RootFilter returns the relative path from a dir of the output back to its root, e.g. "../.." for "api/v1".
+func RootFilter(dir string) string {
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	if dir == "." {
+		return "."
+	}
+
+	return strings.TrimSuffix(strings.Repeat("../", strings.Count(dir, "/")+1), "/")
+}
+
 // PFilter splits the content by new lines and wraps each one in a <p> tag.
 func PFilter(content string) template.HTML {
 	paragraphs := paraPattern.Split(content, -1)
@@ -0,0 +1,357 @@
+package build
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ndjsonLines is the number of messages in NDJSON examples of streaming methods.
+const ndjsonLines = 2
+
+// YAMLString formats the json example as YAML, keeping the key order.
+func (m Message) YAMLString(deep int) string {
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	_ = enc.Encode(yamlNode(m.deepJSONObject(m.JSONObject, deep)))
+	_ = enc.Close()
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func yamlNode(v interface{}) *yaml.Node {
+	switch x := v.(type) {
+	case *OrderedObject, map[string]interface{}:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, key := range objectKeys(x) {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+				yamlNode(objectValue(x, key)))
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range x {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case []string:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range x {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: x}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(x)}
+	default:
+		s := fmt.Sprint(x)
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: s}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: s}
+	}
+}
+
+// TextString formats the json example in protobuf text format, e.g. for test fixtures.
+// Keys which are no fields, like $ref markers, are written as comments.
+func (m Message) TextString(deep int) string {
+	var sb strings.Builder
+	writeTextMessage(&sb, m.deepJSONObject(m.JSONObject, deep), &m, m.messages, "")
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func writeTextMessage(sb *strings.Builder, obj interface{}, message *Message, messages map[string]*Message, indent string) {
+	for _, key := range objectKeys(obj) {
+		v := objectValue(obj, key)
+
+		var f *MessageField
+		if message != nil {
+			f = message.field(key)
+		}
+
+		switch {
+		case f == nil && message != nil:
+			bs, _ := json.Marshal(v)
+			fmt.Fprintf(sb, "%s# %s: %s\n", indent, key, bs)
+		case f == nil:
+			// registered types have no fields, their json keys are taken as field names
+			writeTextField(sb, snakeCase(key), nil, "", v, messages, indent)
+		case f.Ismap:
+			for _, k := range objectKeys(v) {
+				fmt.Fprintf(sb, "%s%s {\n", indent, f.Name)
+				fmt.Fprintf(sb, "%s  key: %s\n", indent, textScalar(f.KeyFullType, k))
+				writeTextField(sb, "value", f, f.FullType, objectValue(v, k), messages, indent+"  ")
+				fmt.Fprintf(sb, "%s}\n", indent)
+			}
+		case f.Isarray:
+			list, _ := v.([]interface{})
+			for _, item := range list {
+				writeTextField(sb, f.Name, f, f.FullType, item, messages, indent)
+			}
+		default:
+			writeTextField(sb, f.Name, f, f.FullType, v, messages, indent)
+		}
+	}
+}
+
+// writeTextField writes a single value of field f, or of an unknown type if f is nil. The packed
+// messages of Any are resolved by full name in messages.
+func writeTextField(sb *strings.Builder, name string, f *MessageField, fullType string, v interface{}, messages map[string]*Message, indent string) {
+	if v == nil {
+		return
+	}
+
+	var message *Message
+	if f != nil {
+		message = f.messageType
+	}
+
+	nested := func(write func(indent string)) {
+		fmt.Fprintf(sb, "%s%s {\n", indent, name)
+		write(indent + "  ")
+		fmt.Fprintf(sb, "%s}\n", indent)
+	}
+
+	switch {
+	case fullType == "google.protobuf.Any":
+		if _, ok := objectValue(v, "@type").(string); !ok {
+			bs, _ := json.Marshal(v)
+			fmt.Fprintf(sb, "%s# %s: %s\n", indent, name, bs)
+			return
+		}
+
+		nested(func(indent string) {
+			writeTextAny(sb, v, messages, indent)
+		})
+
+	case message != nil || (isJSONObject(v) && fullType != "google.protobuf.Struct" && fullType != "google.protobuf.Value"):
+		nested(func(indent string) {
+			writeTextMessage(sb, v, message, messages, indent)
+		})
+
+	case f != nil && f.enumType != nil:
+		fmt.Fprintf(sb, "%s%s: %v\n", indent, name, v)
+
+	case wrapperKinds[fullType] != "" && fullType != "google.protobuf.Duration" && fullType != "google.protobuf.Timestamp":
+		nested(func(indent string) {
+			fmt.Fprintf(sb, "%svalue: %s\n", indent, textScalar(wrapperKinds[fullType], v))
+		})
+
+	case fullType == "google.protobuf.Timestamp":
+		nested(func(indent string) {
+			t, err := time.Parse(time.RFC3339Nano, fmt.Sprint(v))
+			if err != nil {
+				fmt.Fprintf(sb, "%s# %v\n", indent, v)
+				return
+			}
+
+			writeTextSecondsNanos(sb, t.Unix(), int64(t.Nanosecond()), indent)
+		})
+
+	case fullType == "google.protobuf.Duration":
+		nested(func(indent string) {
+			d, err := time.ParseDuration(fmt.Sprint(v))
+			if err != nil {
+				fmt.Fprintf(sb, "%s# %v\n", indent, v)
+				return
+			}
+
+			writeTextSecondsNanos(sb, int64(d/time.Second), int64(d%time.Second), indent)
+		})
+
+	case fullType == "google.protobuf.FieldMask":
+		nested(func(indent string) {
+			for _, path := range strings.Split(fmt.Sprint(v), ",") {
+				fmt.Fprintf(sb, "%spaths: %s\n", indent, strconv.Quote(snakeCase(path)))
+			}
+		})
+
+	case fullType == "google.protobuf.Struct" || fullType == "google.protobuf.Value" || fullType == "google.protobuf.ListValue":
+		nested(func(indent string) {
+			writeTextValue(sb, fullType, v, indent)
+		})
+
+	default:
+		fmt.Fprintf(sb, "%s%s: %s\n", indent, name, textScalar(fullType, v))
+	}
+}
+
+// writeTextAny writes the packed message of a google.protobuf.Any in the expanded form
+// [type.googleapis.com/x.Y] { ... }, well-known types are packed in "value".
+func writeTextAny(sb *strings.Builder, v interface{}, messages map[string]*Message, indent string) {
+	typeURL := fmt.Sprint(objectValue(v, "@type"))
+	fullType := typeURL[strings.LastIndex(typeURL, "/")+1:]
+	name := "[" + typeURL + "]"
+
+	if _, ok := wellKnownTypes[fullType]; ok && strings.HasPrefix(fullType, "google.protobuf.") {
+		writeTextField(sb, name, nil, fullType, objectValue(v, "value"), messages, indent)
+		return
+	}
+
+	packed := newOrderedObject()
+	for _, key := range objectKeys(v) {
+		if key != "@type" {
+			packed.Set(key, objectValue(v, key))
+		}
+	}
+
+	fmt.Fprintf(sb, "%s%s {\n", indent, name)
+	writeTextMessage(sb, packed, messages[fullType], messages, indent+"  ")
+	fmt.Fprintf(sb, "%s}\n", indent)
+}
+
+func writeTextSecondsNanos(sb *strings.Builder, seconds, nanos int64, indent string) {
+	if seconds != 0 {
+		fmt.Fprintf(sb, "%sseconds: %d\n", indent, seconds)
+	}
+
+	if nanos != 0 {
+		fmt.Fprintf(sb, "%snanos: %d\n", indent, nanos)
+	}
+}
+
+// writeTextValue writes the fields of a google.protobuf.Struct, Value or ListValue.
+func writeTextValue(sb *strings.Builder, fullType string, v interface{}, indent string) {
+	switch fullType {
+	case "google.protobuf.Struct":
+		for _, key := range objectKeys(v) {
+			fmt.Fprintf(sb, "%sfields {\n", indent)
+			fmt.Fprintf(sb, "%s  key: %s\n", indent, strconv.Quote(key))
+			fmt.Fprintf(sb, "%s  value {\n", indent)
+			writeTextValue(sb, "google.protobuf.Value", objectValue(v, key), indent+"    ")
+			fmt.Fprintf(sb, "%s  }\n", indent)
+			fmt.Fprintf(sb, "%s}\n", indent)
+		}
+		return
+
+	case "google.protobuf.ListValue":
+		list, _ := v.([]interface{})
+		for _, item := range list {
+			fmt.Fprintf(sb, "%svalues {\n", indent)
+			writeTextValue(sb, "google.protobuf.Value", item, indent+"  ")
+			fmt.Fprintf(sb, "%s}\n", indent)
+		}
+		return
+	}
+
+	switch x := v.(type) {
+	case nil:
+		fmt.Fprintf(sb, "%snull_value: NULL_VALUE\n", indent)
+	case bool:
+		fmt.Fprintf(sb, "%sbool_value: %t\n", indent, x)
+	case string:
+		fmt.Fprintf(sb, "%sstring_value: %s\n", indent, strconv.Quote(x))
+	case []interface{}:
+		fmt.Fprintf(sb, "%slist_value {\n", indent)
+		writeTextValue(sb, "google.protobuf.ListValue", x, indent+"  ")
+		fmt.Fprintf(sb, "%s}\n", indent)
+	case *OrderedObject, map[string]interface{}:
+		fmt.Fprintf(sb, "%sstruct_value {\n", indent)
+		writeTextValue(sb, "google.protobuf.Struct", x, indent+"  ")
+		fmt.Fprintf(sb, "%s}\n", indent)
+	default:
+		fmt.Fprintf(sb, "%snumber_value: %v\n", indent, x)
+	}
+}
+
+// textScalar formats a json scalar of a proto type in text format, 64 bit integers are
+// unquoted and bytes are decoded from base64.
+func textScalar(protoType string, v interface{}) string {
+	s := fmt.Sprint(v)
+
+	switch protoType {
+	case "string":
+		return strconv.Quote(s)
+	case "bytes":
+		if bs, err := base64.StdEncoding.DecodeString(s); err == nil {
+			return strconv.Quote(string(bs))
+		}
+		return strconv.Quote(s)
+	case "bool":
+		return s
+	case "double", "float":
+		switch s {
+		case "NaN":
+			return "nan"
+		case "Infinity":
+			return "inf"
+		case "-Infinity":
+			return "-inf"
+		}
+	}
+
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+
+	if _, ok := v.(bool); ok {
+		return s
+	}
+
+	// type names in legacy mode
+	return strconv.Quote(s)
+}
+
+// snakeCase converts a json name back to the proto field name, e.g. "createTime" to "create_time".
+func snakeCase(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// RequestNDJSON formats the example of a streaming request as newline delimited json.
+func (m ServiceMethod) RequestNDJSON() string {
+	if !m.RequestStreaming {
+		return ""
+	}
+
+	return ndjson(m.RequestExample, m.requestMessage)
+}
+
+// ResponseNDJSON formats the example of a streaming response as newline delimited json.
+func (m ServiceMethod) ResponseNDJSON() string {
+	if !m.ResponseStreaming {
+		return ""
+	}
+
+	return ndjson(m.ResponseExample, m.responseMessage)
+}
+
+func ndjson(example interface{}, message *Message) string {
+	if example == nil && message != nil && message.JSONObject != nil {
+		example = message.JSONObject
+	}
+
+	bs, err := json.Marshal(example)
+	if err != nil {
+		return ""
+	}
+
+	lines := make([]string, ndjsonLines)
+	for i := range lines {
+		lines[i] = string(bs)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,140 @@
+package build
+
+import (
+	"regexp"
+	"strings"
+)
+
+// HTTPRule is a http binding of a method declared with the google.api.http option, see
+// https://github.com/googleapis/googleapis/blob/master/google/api/http.proto
+type HTTPRule struct {
+	Method       string `json:"method"`
+	Pattern      string `json:"pattern"`
+	Body         string `json:"body,omitempty"`
+	ResponseBody string `json:"responseBody,omitempty"`
+	// PathParams are the field paths bound to path variables, e.g. "user.id"
+	PathParams []string `json:"-"`
+	// QueryParams are the request fields passed as url query parameters
+	QueryParams []string `json:"-"`
+	// BodyFields are the request fields sent in the request body
+	BodyFields []string `json:"-"`
+}
+
+// pathVariablePattern matches the variables of a path template, e.g. {id} or {name=users/*}
+var pathVariablePattern = regexp.MustCompile(`{([^=}]+)(=[^}]*)?}`)
+
+// resolveHTTPRules parses the google.api.http option of all methods and maps the request
+// fields to path params, query params and body. Methods bound to the same path and verb are
+// reported.
+func (tmpl *Template) resolveHTTPRules() {
+	bound := make(map[string]string)
+
+	for _, file := range tmpl.Files {
+		for _, service := range file.Services {
+			for _, method := range service.Methods {
+				method.HTTPRules = parseHTTPRules(method.Options.Get("google.api.http"))
+
+				for _, rule := range method.HTTPRules {
+					rule.bindFields(tmpl.messages[method.RequestFullType])
+
+					name := service.FullName + "." + method.Name
+					key := rule.Method + " " + rule.Pattern
+					if other, ok := bound[key]; ok {
+						tmpl.errorf(service.Filename, name, "http binding %s already bound by %s", key, other)
+						continue
+					}
+					bound[key] = name
+				}
+			}
+		}
+	}
+}
+
+// parseHTTPRules reads the option either as decoded from descriptors, i.e. a google.api.HttpRule
+// map, or in the {"rules": [...]} form of protoc-gen-doc.
+func parseHTTPRules(option interface{}) []*HTTPRule {
+	v, ok := option.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var rules []*HTTPRule
+
+	if list, ok := v["rules"].([]interface{}); ok {
+		for _, item := range list {
+			x, _ := item.(map[string]interface{})
+			rule := &HTTPRule{
+				Method:  strings.ToUpper(stringValue(x["method"])),
+				Pattern: stringValue(x["pattern"]),
+				Body:    stringValue(x["body"]),
+			}
+
+			if rule.Pattern != "" {
+				rules = append(rules, rule)
+			}
+		}
+
+		return rules
+	}
+
+	rule := &HTTPRule{
+		Body:         stringValue(v["body"]),
+		ResponseBody: stringValue(v["response_body"]),
+	}
+
+	for _, method := range []string{"get", "put", "post", "delete", "patch"} {
+		if pattern := stringValue(v[method]); pattern != "" {
+			rule.Method = strings.ToUpper(method)
+			rule.Pattern = pattern
+		}
+	}
+
+	if custom, ok := v["custom"].(map[string]interface{}); ok {
+		rule.Method = strings.ToUpper(stringValue(custom["kind"]))
+		rule.Pattern = stringValue(custom["path"])
+	}
+
+	if rule.Pattern != "" {
+		rules = append(rules, rule)
+	}
+
+	bindings, _ := v["additional_bindings"].([]interface{})
+	for _, binding := range bindings {
+		rules = append(rules, parseHTTPRules(binding)...)
+	}
+
+	return rules
+}
+
+// bindFields assigns the top level fields of the request message that are not bound to the
+// path to either the body or the query.
+func (r *HTTPRule) bindFields(request *Message) {
+	inPath := make(map[string]bool)
+
+	for _, match := range pathVariablePattern.FindAllStringSubmatch(r.Pattern, -1) {
+		path := strings.TrimSpace(match[1])
+		r.PathParams = append(r.PathParams, path)
+		inPath[strings.SplitN(path, ".", 2)[0]] = true
+	}
+
+	if request == nil {
+		return
+	}
+
+	for _, field := range request.Fields {
+		switch {
+		case r.Body == field.Name:
+			r.BodyFields = append(r.BodyFields, field.Name)
+		case inPath[field.Name]:
+		case r.Body == "*":
+			r.BodyFields = append(r.BodyFields, field.Name)
+		default:
+			r.QueryParams = append(r.QueryParams, field.Name)
+		}
+	}
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
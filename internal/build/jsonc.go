@@ -0,0 +1,138 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSONCString formats the json example like JSONString, each field is followed by a
+// comment with its type, constraints and description, e.g.
+// "name": "string", // string, 1–64 chars - user name
+func (m Message) JSONCString(deep int) string {
+	var sb strings.Builder
+	writeJSONC(&sb, m.deepJSONObject(m.JSONObject, deep), &m, false, "", "", true)
+	return sb.String()
+}
+
+// Comment returns the comment of the field in JSONC examples.
+func (f MessageField) Comment() string {
+	typ := f.LongType
+	switch {
+	case f.Ismap:
+		typ = fmt.Sprintf("map<%s, %s>", f.KeyLongType, f.LongType)
+	case f.Isarray:
+		typ = "[]" + f.LongType
+	}
+
+	parts := []string{typ}
+	if f.Isoneof {
+		parts = append(parts, "oneof "+f.Oneofdecl)
+	}
+
+	if f.Options.Bool("deprecated") {
+		parts = append(parts, "deprecated")
+	}
+
+	if validator := f.Options.Validator(); validator != nil {
+		parts = append(parts, validator.Constraints()...)
+	}
+
+	comment := strings.Join(parts, ", ")
+	if desc := strings.Join(strings.Fields(f.Description), " "); desc != "" {
+		comment += " - " + desc
+	}
+
+	return comment
+}
+
+// field returns the field of the message named key in json examples, i.e. its json name
+// or in legacy mode its proto name.
+func (m *Message) field(key string) *MessageField {
+	for _, f := range m.Fields {
+		if jsonName(f) == key || f.Name == key {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// writeJSONC writes v indented like json.MarshalIndent followed by a comma unless it is the
+// last member. comment belongs to the line where v starts. message is the message v is an
+// example of, or the message of the values if v is a map field.
+func writeJSONC(sb *strings.Builder, v interface{}, message *Message, isMap bool, indent, comment string, last bool) {
+	comma := ","
+	if last {
+		comma = ""
+	}
+
+	open := func(brackets string) {
+		sb.WriteString(brackets)
+		if len(brackets) == 2 {
+			sb.WriteString(comma)
+		}
+		if comment != "" {
+			sb.WriteString(" // " + comment)
+		}
+	}
+
+	switch x := v.(type) {
+	case map[string]interface{}, *OrderedObject:
+		keys := objectKeys(x)
+		if len(keys) == 0 {
+			open("{}")
+			return
+		}
+
+		open("{")
+		for i, k := range keys {
+			sb.WriteString("\n" + indent + "  ")
+			writeJSONScalar(sb, k)
+			sb.WriteString(": ")
+
+			last := i == len(keys)-1
+			value := objectValue(x, k)
+
+			var f *MessageField
+			if message != nil && !isMap {
+				f = message.field(k)
+			}
+
+			switch {
+			case isMap:
+				writeJSONC(sb, value, message, false, indent+"  ", "", last)
+			case f != nil:
+				writeJSONC(sb, value, f.messageType, f.Ismap, indent+"  ", f.Comment(), last)
+			default:
+				writeJSONC(sb, value, nil, false, indent+"  ", "", last)
+			}
+		}
+		sb.WriteString("\n" + indent + "}" + comma)
+
+	case []interface{}:
+		if len(x) == 0 {
+			open("[]")
+			return
+		}
+
+		open("[")
+		for i, item := range x {
+			sb.WriteString("\n" + indent + "  ")
+			writeJSONC(sb, item, message, false, indent+"  ", "", i == len(x)-1)
+		}
+		sb.WriteString("\n" + indent + "]" + comma)
+
+	default:
+		writeJSONScalar(sb, x)
+		sb.WriteString(comma)
+		if comment != "" {
+			sb.WriteString(" // " + comment)
+		}
+	}
+}
+
+func writeJSONScalar(sb *strings.Builder, v interface{}) {
+	bs, _ := json.Marshal(v)
+	sb.Write(bs)
+}
@@ -0,0 +1,86 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmlTemplate "html/template"
+	"io/fs"
+	"path"
+)
+
+// DefaultLang is the language of the docs if none is given, messages missing in the catalog
+// of another language fall back to it.
+const DefaultLang = "zh"
+
+// loadCatalog reads the message catalog of the language from "locales/<lang>.json" of the
+// templates, so a templates dir may add languages or override single messages.
+func (r *Renderer) loadCatalog() error {
+	lang := r.lang
+	if lang == "" {
+		lang = DefaultLang
+	}
+
+	r.catalog = make(map[string]string)
+
+	for _, l := range []string{DefaultLang, lang} {
+		name := path.Join("locales", l+".json")
+
+		bs, err := r.readTemplate(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if r.templates != "" {
+				return fmt.Errorf("unknown lang: %s, %s is neither in the templates dir %s nor embedded", l, name, r.templates)
+			}
+
+			return fmt.Errorf("unknown lang: %s, %s is not embedded, add it to a templates dir", l, name)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var catalog map[string]string
+		err = json.Unmarshal(bs, &catalog)
+		if err != nil {
+			return fmt.Errorf("decode catalog failure, lang: %s, err: %w", l, err)
+		}
+
+		for key, message := range catalog {
+			r.catalog[key] = message
+		}
+	}
+
+	return nil
+}
+
+// checkLang reports a language without catalog in the templates dir or the embedded templates.
+func checkLang(templatesDir, lang string) error {
+	r := &Renderer{templates: templatesDir, lang: lang}
+	return r.loadCatalog()
+}
+
+// translate is the "t" function of the templates, it returns the message of key formatted
+// with args, or key itself if no catalog has it. Messages are trusted like the templates,
+// args are escaped unless they are template.HTML already.
+func (r *Renderer) translate(key string, args ...interface{}) htmlTemplate.HTML {
+	message, ok := r.catalog[key]
+	if !ok {
+		message = key
+	}
+
+	if len(args) > 0 {
+		escaped := make([]interface{}, len(args))
+		for i, arg := range args {
+			if html, ok := arg.(htmlTemplate.HTML); ok {
+				escaped[i] = html
+				continue
+			}
+
+			escaped[i] = htmlTemplate.HTMLEscapeString(fmt.Sprint(arg))
+		}
+
+		message = fmt.Sprintf(message, escaped...)
+	}
+
+	return htmlTemplate.HTML(message)
+}
@@ -0,0 +1,16 @@
+package build
+
+import (
+	htmlTemplate "html/template"
+	"testing"
+)
+
+func TestTranslateEscapesArgs(t *testing.T) {
+	r := &Renderer{catalog: map[string]string{"note": "%s <i>and</i> %s"}}
+
+	got := r.translate("note", "Get doc <b>x</b>", htmlTemplate.HTML("<code>y</code>"))
+	want := htmlTemplate.HTML("Get doc &lt;b&gt;x&lt;/b&gt; <i>and</i> <code>y</code>")
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Key orders of the json examples.
+const (
+	// OrderDeclaration keeps the fields in the order they are declared in the proto file
+	OrderDeclaration = "declaration"
+	// OrderNumber sorts the fields by field number
+	OrderNumber = "number"
+	// OrderName sorts the keys alphabetically, i.e. by json name or by proto name in legacy mode
+	OrderName = "name"
+)
+
+func checkJSONOrder(order string) error {
+	switch order {
+	case "", OrderDeclaration, OrderNumber, OrderName:
+		return nil
+	default:
+		return fmt.Errorf("unknown json order: %s, expect %s, %s or %s", order, OrderDeclaration, OrderNumber, OrderName)
+	}
+}
+
+// OrderedObject is a json object keeping its keys in the order they were set.
+type OrderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedObject() *OrderedObject {
+	return &OrderedObject{values: make(map[string]interface{})}
+}
+
+// Set sets the value of key, a new key is appended.
+func (o *OrderedObject) Set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+
+	o.values[key] = v
+}
+
+// Get returns the value of key.
+func (o *OrderedObject) Get(key string) (interface{}, bool) {
+	if o == nil {
+		return nil, false
+	}
+
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// Keys returns the keys in order.
+func (o *OrderedObject) Keys() []string {
+	if o == nil {
+		return nil
+	}
+
+	return o.keys
+}
+
+// Len returns the number of keys.
+func (o *OrderedObject) Len() int {
+	return len(o.Keys())
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o *OrderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// orderedFields returns the fields of the message in the order of tmpl.JSONOrder.
+func (tmpl *Template) orderedFields(message *Message) []*MessageField {
+	if tmpl.JSONOrder != OrderNumber {
+		return message.Fields
+	}
+
+	fields := make([]*MessageField, len(message.Fields))
+	copy(fields, message.Fields)
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Number < fields[j].Number
+	})
+
+	return fields
+}
+
+// objectKeys returns the keys of a json object, ordered objects keep their order and maps
+// are sorted as encoding/json does.
+func objectKeys(obj interface{}) []string {
+	switch x := obj.(type) {
+	case *OrderedObject:
+		return x.Keys()
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+		return keys
+	default:
+		return nil
+	}
+}
+
+// objectValue returns the value of key in an ordered object or a map.
+func objectValue(obj interface{}, key string) interface{} {
+	switch x := obj.(type) {
+	case *OrderedObject:
+		v, _ := x.Get(key)
+		return v
+	case map[string]interface{}:
+		return x[key]
+	default:
+		return nil
+	}
+}
+
+// decodeOrderedJSON decodes json keeping the key order of objects in OrderedObject, numbers
+// are kept as json.Number so that they are written back as given.
+func decodeOrderedJSON(bs []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+
+	v, err := decodeOrderedValue(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after json value")
+	}
+
+	return v, nil
+}
+
+func decodeOrderedValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		obj := newOrderedObject()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			obj.Set(key.(string), v)
+		}
+
+		_, err = dec.Token()
+		return obj, err
+
+	case json.Delim('['):
+		list := make([]interface{}, 0)
+		for dec.More() {
+			v, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, v)
+		}
+
+		_, err = dec.Token()
+		return list, err
+
+	default:
+		return token, nil
+	}
+}
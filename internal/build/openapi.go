@@ -0,0 +1,280 @@
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIVersion is the version of the generated OpenAPI documents.
+const openAPIVersion = "3.1.0"
+
+// renderOpenAPI writes openapi.yaml describing the methods with google.api.http bindings,
+// nothing is written if there are none.
+func (r *Renderer) renderOpenAPI(path string) error {
+	doc := r.tmpl.OpenAPI()
+	if paths, _ := doc.Get("paths"); paths.(*OrderedObject).Len() == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	err := enc.Encode(yamlNode(doc))
+	if err != nil {
+		return fmt.Errorf("encode openapi failure, err: %w", err)
+	}
+
+	_ = enc.Close()
+
+	fp, err := r.createFile(filepath.Join(path, "openapi.yaml"))
+	if err != nil {
+		return err
+	}
+
+	_, err = fp.Write(buf.Bytes())
+	_ = fp.Close()
+
+	return err
+}
+
+// OpenAPI returns the OpenAPI 3.1 document of the http bindings of all methods, messages are
+// component schemas keyed by full name. Bindings whose paths only differ in the patterns of
+// their variables are one OpenAPI path, the first one is kept and the others are warned about.
+// The document is built once, call it before writing the diagnostics.
+func (tmpl *Template) OpenAPI() *OrderedObject {
+	if tmpl.openAPI != nil {
+		return tmpl.openAPI
+	}
+
+	b := &schemaBuilder{prefix: "#/components/schemas/", defs: newOrderedObject()}
+	bound := make(map[string]string)
+
+	var packages []string
+	paths := newOrderedObject()
+	var tags []interface{}
+
+	for _, file := range tmpl.Files {
+		for _, service := range file.Services {
+			var operations int
+
+			for _, method := range service.Methods {
+				for i, rule := range method.HTTPRules {
+					operationID := service.Name + "_" + method.Name
+					if i > 0 {
+						operationID += fmt.Sprintf("_%d", i)
+					}
+
+					item, ok := paths.Get(openAPIPath(rule.Pattern))
+					if !ok {
+						item = newOrderedObject()
+						paths.Set(openAPIPath(rule.Pattern), item)
+					}
+
+					name := service.FullName + "." + method.Name
+					key := rule.Method + " " + openAPIPath(rule.Pattern)
+					if other, ok := bound[key]; ok {
+						// bindings of the very same pattern are reported by resolveHTTPRules
+						if other != rule.Pattern {
+							tmpl.warnf(service.Filename, name, "http binding %s %s is left out of the OpenAPI document, its path is the same as %s", rule.Method, rule.Pattern, other)
+						}
+						continue
+					}
+					bound[key] = rule.Pattern
+
+					item.(*OrderedObject).Set(strings.ToLower(rule.Method), b.operation(service, method, rule, operationID))
+					operations++
+				}
+			}
+
+			if operations == 0 {
+				continue
+			}
+
+			tag := newOrderedObject()
+			tag.Set("name", service.Name)
+			if desc := strings.TrimSpace(service.Description); desc != "" {
+				tag.Set("description", desc)
+			}
+			tags = append(tags, tag)
+
+			if !contains(packages, file.Package) {
+				packages = append(packages, file.Package)
+			}
+		}
+	}
+
+	info := newOrderedObject()
+	info.Set("title", strings.Join(packages, ", "))
+	info.Set("version", "1.0.0")
+
+	doc := newOrderedObject()
+	doc.Set("openapi", openAPIVersion)
+	doc.Set("info", info)
+	if len(tags) > 0 {
+		doc.Set("tags", tags)
+	}
+	doc.Set("paths", paths)
+
+	if b.defs.Len() > 0 {
+		components := newOrderedObject()
+		components.Set("schemas", b.sortedDefs())
+		doc.Set("components", components)
+	}
+
+	tmpl.openAPI = doc
+	return doc
+}
+
+func (b *schemaBuilder) operation(service *Service, method *ServiceMethod, rule *HTTPRule, operationID string) *OrderedObject {
+	op := newOrderedObject()
+	op.Set("tags", []string{service.Name})
+
+	if desc := strings.TrimSpace(method.Description); desc != "" {
+		op.Set("summary", strings.SplitN(desc, "\n", 2)[0])
+		op.Set("description", desc)
+	}
+
+	op.Set("operationId", operationID)
+
+	if method.Options.Bool("deprecated") {
+		op.Set("deprecated", true)
+	}
+
+	request := method.requestMessage
+
+	var parameters []interface{}
+	for _, name := range rule.PathParams {
+		param := newOrderedObject()
+		param.Set("name", name)
+		param.Set("in", "path")
+		param.Set("required", true)
+		param.Set("schema", b.parameterSchema(request, name))
+		parameters = append(parameters, param)
+	}
+
+	for _, name := range rule.QueryParams {
+		param := newOrderedObject()
+		param.Set("name", name)
+		param.Set("in", "query")
+		if f := fieldPath(request, name); f != nil {
+			if schema, required := b.fieldSchema(f); schema != nil {
+				if desc, ok := schema.Get("description"); ok {
+					param.Set("description", desc)
+				}
+				if required {
+					param.Set("required", true)
+				}
+				param.Set("schema", schema)
+			}
+		}
+		parameters = append(parameters, param)
+	}
+
+	if len(parameters) > 0 {
+		op.Set("parameters", parameters)
+	}
+
+	if rule.Body != "" && request != nil {
+		var schema *OrderedObject
+		if rule.Body == "*" {
+			schema = b.messageRef(request)
+		} else if f := fieldPath(request, rule.Body); f != nil {
+			schema, _ = b.fieldSchema(f)
+		}
+
+		if schema != nil {
+			body := newOrderedObject()
+			body.Set("required", true)
+			body.Set("content", jsonContent(schema))
+			op.Set("requestBody", body)
+		}
+	}
+
+	response := newOrderedObject()
+	response.Set("description", "OK")
+	if method.responseMessage != nil {
+		schema := b.messageRef(method.responseMessage)
+		if rule.ResponseBody != "" {
+			if f := fieldPath(method.responseMessage, rule.ResponseBody); f != nil {
+				schema, _ = b.fieldSchema(f)
+			}
+		}
+		response.Set("content", jsonContent(schema))
+	}
+
+	responses := newOrderedObject()
+	responses.Set("200", response)
+	op.Set("responses", responses)
+
+	return op
+}
+
+// parameterSchema returns the schema of a path parameter, path params are always strings
+// in the url, the field type only refines the schema.
+func (b *schemaBuilder) parameterSchema(request *Message, name string) *OrderedObject {
+	if f := fieldPath(request, name); f != nil && f.messageType == nil {
+		schema, _ := b.fieldSchema(f)
+		return schema
+	}
+
+	schema := newOrderedObject()
+	schema.Set("type", "string")
+	return schema
+}
+
+func jsonContent(schema *OrderedObject) *OrderedObject {
+	mediaType := newOrderedObject()
+	mediaType.Set("schema", schema)
+
+	content := newOrderedObject()
+	content.Set("application/json", mediaType)
+	return content
+}
+
+// openAPIPath converts a path template to an OpenAPI path, e.g. "/v1/{name=users/*}" to "/v1/{name}".
+func openAPIPath(pattern string) string {
+	return pathVariablePattern.ReplaceAllString(pattern, "{$1}")
+}
+
+// fieldPath returns the field of a dotted path of proto field names, e.g. "user.id".
+func fieldPath(message *Message, path string) *MessageField {
+	var field *MessageField
+
+	for _, name := range strings.Split(path, ".") {
+		if message == nil {
+			return nil
+		}
+
+		field = nil
+		for _, f := range message.Fields {
+			if f.Name == name {
+				field = f
+				break
+			}
+		}
+
+		if field == nil {
+			return nil
+		}
+
+		message = field.messageType
+	}
+
+	return field
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,239 @@
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// CommandPlugin is used to run as protoc plugin, it reads a CodeGeneratorRequest from stdin
+// and writes a CodeGeneratorResponse to stdout
+// buf.gen.yaml: plugins: [{plugin: gendoc, path: [proto-gen-doc, plugin], out: doc}]
+func CommandPlugin() *cobra.Command {
+	return &cobra.Command{
+		Use:   "plugin",
+		Short: "run as protoc / buf plugin.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := RunPlugin(os.Stdin, os.Stdout)
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "execute %s error: %v\n", cmd.Name(), err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// RunPlugin renders the files of a CodeGeneratorRequest. Errors about the request are
+// reported in the response, the returned error is about reading or writing only.
+func RunPlugin(in io.Reader, out io.Writer) error {
+	bs, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("read request failure: %w", err)
+	}
+
+	var req pluginpb.CodeGeneratorRequest
+	err = proto.Unmarshal(bs, &req)
+	if err != nil {
+		return fmt.Errorf("decode request failure: %w", err)
+	}
+
+	resp := generate(&req)
+
+	bs, err = proto.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("encode response failure: %w", err)
+	}
+
+	_, err = out.Write(bs)
+	if err != nil {
+		return fmt.Errorf("write response failure: %w", err)
+	}
+
+	return nil
+}
+
+func generate(req *pluginpb.CodeGeneratorRequest) *pluginpb.CodeGeneratorResponse {
+	resp := &pluginpb.CodeGeneratorResponse{
+		SupportedFeatures: proto.Uint64(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)),
+	}
+
+	fail := func(err error) *pluginpb.CodeGeneratorResponse {
+		resp.Error = proto.String(err.Error())
+		return resp
+	}
+
+	cfg, err := parsePluginParameter(req.GetParameter())
+	if err != nil {
+		return fail(err)
+	}
+
+	files, err := linkFileDescriptors(req.GetProtoFile(), req.GetFileToGenerate())
+	if err != nil {
+		return fail(err)
+	}
+
+	tmpl := Template{
+		LegacyJSON: cfg.LegacyJSON,
+		JSONOrder:  cfg.JSONOrder,
+	}
+
+	if cfg.TypesFile != "" {
+		err = tmpl.LoadTypes(cfg.TypesFile)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if cfg.ExamplesDir != "" {
+		err = tmpl.LoadExamples(cfg.ExamplesDir)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	err = tmpl.ParseDescriptors(files...)
+	if err != nil {
+		return fail(fmt.Errorf("parse descriptors failure: %w", err))
+	}
+
+	if cfg.OpenAPI {
+		// the OpenAPI document reports the bindings it leaves out
+		tmpl.OpenAPI()
+	}
+
+	tmpl.WriteDiagnostics(os.Stderr)
+
+	if cfg.Strict {
+		err = strictError(&tmpl)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	output := new(memoryOutput)
+	renderer := &Renderer{
+		tmpl:      &tmpl,
+		output:    output,
+		format:    cfg.Format,
+		templates: cfg.TemplatesDir,
+		lang:      cfg.Lang,
+		schema:    cfg.JSONSchema,
+		openAPI:   cfg.OpenAPI,
+	}
+
+	err = renderer.Render(cfg.Output)
+	if err != nil {
+		return fail(fmt.Errorf("render proto file failure: %w", err))
+	}
+
+	if cfg.DiagnosticsFile != "" {
+		bs, err := tmpl.Report().JSON()
+		if err != nil {
+			return fail(fmt.Errorf("encode diagnostics failure: %w", err))
+		}
+
+		file, _ := output.Create(cfg.DiagnosticsFile)
+		_, _ = file.Write(append(bs, '\n'))
+	}
+
+	for _, file := range output.files {
+		resp.File = append(resp.File, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(filepath.ToSlash(file.name)),
+			Content: proto.String(file.String()),
+		})
+	}
+
+	return resp
+}
+
+// parsePluginParameter parses the comma separated key=value pairs passed to the plugin, e.g.
+// --gendoc_out=prefix=docs:../out
+func parsePluginParameter(parameter string) (*Config, error) {
+	cfg := new(Config)
+
+	for _, param := range strings.Split(parameter, ",") {
+		if param = strings.TrimSpace(param); param == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(param, "=")
+
+		switch key {
+		case "prefix":
+			cfg.Output = value
+		case "legacy_json":
+			cfg.LegacyJSON = value == "" || value == "true"
+		case "types":
+			cfg.TypesFile = value
+		case "json_schema":
+			cfg.JSONSchema = value == "" || value == "true"
+		case "openapi":
+			cfg.OpenAPI = value == "" || value == "true"
+		case "strict":
+			cfg.Strict = value == "" || value == "true"
+		case "diagnostics":
+			cfg.DiagnosticsFile = value
+		case "examples":
+			cfg.ExamplesDir = value
+		case "json_order":
+			err := checkJSONOrder(value)
+			if err != nil {
+				return nil, err
+			}
+			cfg.JSONOrder = value
+		case "format":
+			err := checkFormat(value)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Format = value
+		case "templates":
+			err := checkTemplatesDir(value)
+			if err != nil {
+				return nil, err
+			}
+			cfg.TemplatesDir = value
+		case "lang":
+			cfg.Lang = value
+		default:
+			return nil, fmt.Errorf("unknown plugin parameter: %s", param)
+		}
+	}
+
+	// the catalog may be in the templates dir, which may be given after the lang
+	err := checkLang(cfg.TemplatesDir, cfg.Lang)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// memoryOutput keeps the rendered files in memory, in the order they were created.
+type memoryOutput struct {
+	files []*memoryFile
+}
+
+type memoryFile struct {
+	bytes.Buffer
+	name string
+}
+
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+// Create implements Output.
+func (o *memoryOutput) Create(filename string) (io.WriteCloser, error) {
+	file := &memoryFile{name: filename}
+	o.files = append(o.files, file)
+	return file, nil
+}
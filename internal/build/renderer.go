@@ -1,24 +1,52 @@
 package build
 
 import (
-	"embed"
-	htmlTemplate "html/template"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+)
 
-	"github.com/Masterminds/sprig"
+// Output formats of the renderer.
+const (
+	// FormatMarkdown writes proto.md files
+	FormatMarkdown = "markdown"
+	// FormatHTML writes a self-contained site of index.html files
+	FormatHTML = "html"
 )
 
-//go:embed tmpl
-var templateFS embed.FS
+func checkFormat(format string) error {
+	switch format {
+	case "", FormatMarkdown, FormatHTML:
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %s, expect %s or %s", format, FormatMarkdown, FormatHTML)
+	}
+}
 
 // Renderer TODO
 type Renderer struct {
-	tmpl     *Template
+	tmpl   *Template
+	output Output
+	format string
+	// templates is the dir of templates overriding the embedded ones
+	templates string
+	// lang selects the message catalog, DefaultLang if empty
+	lang     string
+	catalog  map[string]string
+	schema   bool
+	openAPI  bool
 	ErrFile  *File
 	Packages []*Package
+	Cycles   []*Cycle
+}
+
+// Output creates the files written by the renderer, the files are written to disk if the
+// renderer has no output.
+type Output interface {
+	Create(filename string) (io.WriteCloser, error)
 }
 
 // Package TODO
@@ -68,22 +96,66 @@ func (r *Renderer) Render(path string) error {
 		}
 	}
 
-	err = r.renderTOC(path, "tmpl/proto.toc.md.tmpl", "proto.md")
+	r.Cycles = r.tmpl.Cycles
+
+	err = r.loadCatalog()
 	if err != nil {
 		return err
 	}
 
-	err = r.renderService(path, "tmpl/proto.doc.md.tmpl", "proto.md")
-	if err != nil {
-		return err
+	switch r.format {
+	case FormatHTML:
+		err = r.renderTOC(path, "index.html", "proto.toc.html.tmpl", "style.html.tmpl", "search.html.tmpl")
+		if err != nil {
+			return err
+		}
+
+		err = r.renderService(path, "index.html", "proto.doc.html.tmpl", "style.html.tmpl", "search.html.tmpl")
+		if err != nil {
+			return err
+		}
+
+		err = r.renderSearchIndex(path, "index.html")
+		if err != nil {
+			return err
+		}
+
+	default:
+		err = r.renderTOC(path, "proto.md", "proto.toc.md.tmpl")
+		if err != nil {
+			return err
+		}
+
+		err = r.renderService(path, "proto.md", "proto.doc.md.tmpl")
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.schema {
+		err = r.renderSchemas(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.openAPI {
+		err = r.renderOpenAPI(path)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (r *Renderer) createFile(filename string) (*os.File, error) {
+func (r *Renderer) createFile(filename string) (io.WriteCloser, error) {
 	filename = filepath.Clean(filename)
 
+	if r.output != nil {
+		return r.output.Create(filename)
+	}
+
 	err := os.MkdirAll(filepath.Dir(filename), 0755)
 	if err != nil {
 		return nil, err
@@ -92,16 +164,8 @@ func (r *Renderer) createFile(filename string) (*os.File, error) {
 	return os.Create(filename)
 }
 
-func (r *Renderer) renderTOC(path, templateFile, outputFile string) error {
-	var err error
-
-	templateText, err := templateFS.ReadFile(templateFile)
-	if err != nil {
-		return err
-	}
-
-	template := htmlTemplate.New("TOC Template").Funcs(funcMap).Funcs(sprig.HtmlFuncMap())
-	_, err = template.Parse(string(templateText))
+func (r *Renderer) renderTOC(path, outputFile string, templateFiles ...string) error {
+	template, err := r.parseTemplate("TOC Template", templateFiles...)
 	if err != nil {
 		return err
 	}
@@ -121,16 +185,8 @@ func (r *Renderer) renderTOC(path, templateFile, outputFile string) error {
 	return nil
 }
 
-func (r *Renderer) renderService(path, templateFile, outputFile string) error {
-	var err error
-
-	templateText, err := templateFS.ReadFile(templateFile)
-	if err != nil {
-		return err
-	}
-
-	template := htmlTemplate.New("Service Template").Funcs(funcMap).Funcs(sprig.HtmlFuncMap())
-	_, err = template.Parse(string(templateText))
+func (r *Renderer) renderService(path, outputFile string, templateFiles ...string) error {
+	template, err := r.parseTemplate("Service Template", templateFiles...)
 	if err != nil {
 		return err
 	}
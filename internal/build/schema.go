@@ -0,0 +1,438 @@
+package build
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// schemaDialect is the JSON Schema draft of the generated schemas.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaFormats maps the string formats of validate rules to JSON Schema formats.
+var schemaFormats = map[string]string{
+	"email":    "email",
+	"hostname": "hostname",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"uri":      "uri",
+	"uri_ref":  "uri-reference",
+	"uuid":     "uuid",
+}
+
+// renderSchemas writes the JSON Schema of every message to <dir of file>/schema/<full name>.schema.json.
+func (r *Renderer) renderSchemas(path string) error {
+	for _, file := range r.tmpl.Files {
+		for _, message := range file.Messages {
+			if message.Ismapentry {
+				continue
+			}
+
+			bs, err := json.MarshalIndent(message.JSONSchema(), "", "  ")
+			if err != nil {
+				return err
+			}
+
+			fp, err := r.createFile(filepath.Join(path, file.Dir, "schema", message.FullName+".schema.json"))
+			if err != nil {
+				return err
+			}
+
+			_, err = fp.Write(append(bs, '\n'))
+			_ = fp.Close()
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// schemaBuilder collects the definitions of the messages and enums referenced by a schema.
+type schemaBuilder struct {
+	// root is the message of the schema, it is referenced as "#"
+	root *Message
+	// prefix is prepended to the full names in references, e.g. "#/$defs/"
+	prefix string
+	defs   *OrderedObject
+}
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of the proto3 JSON representation of
+// the message, referenced messages and enums are kept in $defs by full name.
+func (m *Message) JSONSchema() *OrderedObject {
+	b := &schemaBuilder{root: m, prefix: "#/$defs/", defs: newOrderedObject()}
+
+	schema := newOrderedObject()
+	schema.Set("$schema", schemaDialect)
+	schema.Set("$id", m.FullName+".schema.json")
+	schema.Set("title", m.LongName)
+
+	body := b.messageSchema(m)
+	for _, key := range body.Keys() {
+		v, _ := body.Get(key)
+		schema.Set(key, v)
+	}
+
+	if b.defs.Len() > 0 {
+		schema.Set("$defs", b.sortedDefs())
+	}
+
+	return schema
+}
+
+// sortedDefs returns the definitions sorted by full name.
+func (b *schemaBuilder) sortedDefs() *OrderedObject {
+	keys := append([]string(nil), b.defs.Keys()...)
+	sort.Strings(keys)
+
+	defs := newOrderedObject()
+	for _, key := range keys {
+		v, _ := b.defs.Get(key)
+		defs.Set(key, v)
+	}
+
+	return defs
+}
+
+func (b *schemaBuilder) ref(fullName string) *OrderedObject {
+	ref := newOrderedObject()
+	if b.root != nil && fullName == b.root.FullName {
+		ref.Set("$ref", "#")
+	} else {
+		ref.Set("$ref", b.prefix+fullName)
+	}
+	return ref
+}
+
+func (b *schemaBuilder) messageRef(message *Message) *OrderedObject {
+	if _, ok := b.defs.Get(message.FullName); !ok && message != b.root {
+		// reserve the name first, recursive messages refer to it while it is built
+		b.defs.Set(message.FullName, nil)
+		b.defs.Set(message.FullName, b.messageSchema(message))
+	}
+
+	return b.ref(message.FullName)
+}
+
+func (b *schemaBuilder) enumRef(enum *Enum) *OrderedObject {
+	if _, ok := b.defs.Get(enum.FullName); !ok {
+		b.defs.Set(enum.FullName, enumSchema(enum))
+	}
+
+	return b.ref(enum.FullName)
+}
+
+func (b *schemaBuilder) messageSchema(message *Message) *OrderedObject {
+	schema := newOrderedObject()
+	if desc := strings.TrimSpace(message.Description); desc != "" {
+		schema.Set("description", desc)
+	}
+
+	if message.Options.Bool("deprecated") {
+		schema.Set("deprecated", true)
+	}
+
+	schema.Set("type", "object")
+
+	properties := newOrderedObject()
+	var required []string
+
+	for _, f := range message.Fields {
+		prop, isRequired := b.fieldSchema(f)
+		properties.Set(jsonName(f), prop)
+
+		if isRequired {
+			required = append(required, jsonName(f))
+		}
+	}
+
+	schema.Set("properties", properties)
+	if len(required) > 0 {
+		schema.Set("required", required)
+	}
+
+	// at most one member of a oneof may be set
+	dependent := newOrderedObject()
+	for _, oneof := range message.Oneofs() {
+		if len(oneof.Fields) < 2 {
+			continue
+		}
+
+		for _, f := range oneof.Fields {
+			var others []interface{}
+			for _, other := range oneof.Fields {
+				if other != f {
+					others = append(others, map[string]interface{}{"required": []string{jsonName(other)}})
+				}
+			}
+
+			dependent.Set(jsonName(f), map[string]interface{}{"not": map[string]interface{}{"anyOf": others}})
+		}
+	}
+
+	if dependent.Len() > 0 {
+		schema.Set("dependentSchemas", dependent)
+	}
+
+	return schema
+}
+
+// fieldSchema returns the schema of a field and whether the rules require it.
+func (b *schemaBuilder) fieldSchema(f *MessageField) (*OrderedObject, bool) {
+	groups := f.ruleGroups("")
+	isRequired := groups["required"].bool("required") || groups["message"].bool("required")
+
+	var schema *OrderedObject
+	switch {
+	case f.Isarray:
+		schema = newOrderedObject()
+		schema.Set("type", "array")
+		schema.Set("items", b.valueSchema(f, f.FullType, f.ruleGroups("repeated.items.")))
+		applyRules(schema, "repeated", groups["repeated"], nil)
+
+	case f.Ismap:
+		schema = newOrderedObject()
+		schema.Set("type", "object")
+		if keys := applyRules(newOrderedObject(), wrapperKind(f.KeyFullType), f.ruleGroups("map.keys.")[wrapperKind(f.KeyFullType)], nil); keys.Len() > 0 {
+			schema.Set("propertyNames", keys)
+		}
+		schema.Set("additionalProperties", b.valueSchema(f, f.FullType, f.ruleGroups("map.values.")))
+		applyRules(schema, "map", groups["map"], nil)
+
+	default:
+		schema = b.valueSchema(f, f.FullType, groups)
+	}
+
+	if desc := strings.TrimSpace(f.Description); desc != "" {
+		schema.Set("description", desc)
+	}
+
+	if f.Options.Bool("deprecated") {
+		schema.Set("deprecated", true)
+	}
+
+	return schema, isRequired
+}
+
+// valueSchema returns the schema of a single value of the field type with its rules.
+func (b *schemaBuilder) valueSchema(f *MessageField, fullType string, groups map[string]ruleGroup) *OrderedObject {
+	switch {
+	case f.messageType != nil:
+		return b.messageRef(f.messageType)
+
+	case f.enumType != nil:
+		if g, ok := groups["enum"]; ok && (g["in"] != nil || g["not_in"] != nil || g["const"] != nil) {
+			return applyRules(enumSchema(f.enumType), "enum", g, f.enumType)
+		}
+		return b.enumRef(f.enumType)
+	}
+
+	kind := wrapperKind(fullType)
+	return applyRules(typeSchema(fullType), kind, groups[kind], nil)
+}
+
+func wrapperKind(fullType string) string {
+	if kind, ok := wrapperKinds[fullType]; ok {
+		return kind
+	}
+
+	return fullType
+}
+
+func enumSchema(enum *Enum) *OrderedObject {
+	schema := newOrderedObject()
+	if desc := strings.TrimSpace(enum.Description); desc != "" {
+		schema.Set("description", desc)
+	}
+
+	names := make([]interface{}, 0, len(enum.Values))
+	for _, value := range enum.Values {
+		names = append(names, value.Name)
+	}
+
+	schema.Set("type", "string")
+	schema.Set("enum", names)
+	return schema
+}
+
+// typeSchema returns the schema of a scalar or well-known type as the proto3 JSON mapping
+// represents it, other types are accepted as any value.
+func typeSchema(fullType string) *OrderedObject {
+	schema := newOrderedObject()
+
+	switch wrapperKind(fullType) {
+	case "string":
+		schema.Set("type", "string")
+	case "bytes":
+		schema.Set("type", "string")
+		schema.Set("contentEncoding", "base64")
+	case "bool":
+		schema.Set("type", "boolean")
+	case "int32", "sint32", "sfixed32":
+		schema.Set("type", "integer")
+	case "uint32", "fixed32":
+		schema.Set("type", "integer")
+		schema.Set("minimum", 0)
+	case "int64", "sint64", "sfixed64", "uint64", "fixed64":
+		// 64 bit integers are strings in JSON, numbers are accepted too
+		schema.Set("type", []string{"string", "integer"})
+		schema.Set("pattern", "^-?[0-9]+$")
+	case "float", "double":
+		schema.Set("type", "number")
+	case "timestamp":
+		schema.Set("type", "string")
+		schema.Set("format", "date-time")
+	case "duration":
+		schema.Set("type", "string")
+		schema.Set("pattern", `^-?[0-9]+(\.[0-9]+)?s$`)
+	}
+
+	switch fullType {
+	case "google.protobuf.Struct", "google.protobuf.Empty":
+		schema.Set("type", "object")
+	case "google.protobuf.ListValue":
+		schema.Set("type", "array")
+	case "google.protobuf.NullValue":
+		schema.Set("type", "null")
+	case "google.protobuf.FieldMask":
+		schema.Set("type", "string")
+	case "google.protobuf.Any":
+		schema.Set("type", "object")
+		schema.Set("properties", map[string]interface{}{"@type": map[string]interface{}{"type": "string"}})
+		schema.Set("required", []string{"@type"})
+	}
+
+	return schema
+}
+
+// applyRules adds the validate rules of one rule type to the schema.
+func applyRules(schema *OrderedObject, kind string, g ruleGroup, enum *Enum) *OrderedObject {
+	if len(g) == 0 {
+		return schema
+	}
+
+	set := func(name string, rule string) {
+		if v, ok := g[rule]; ok {
+			schema.Set(name, v)
+		}
+	}
+
+	switch kind {
+	case "string":
+		set("minLength", "min_len")
+		set("maxLength", "max_len")
+		set("minLength", "len")
+		set("maxLength", "len")
+
+		var patterns []string
+		if v, ok := g["pattern"]; ok {
+			patterns = append(patterns, formatRuleValue(v))
+		}
+		if v, ok := g["prefix"]; ok {
+			patterns = append(patterns, "^"+regexp.QuoteMeta(formatRuleValue(v)))
+		}
+		if v, ok := g["suffix"]; ok {
+			patterns = append(patterns, regexp.QuoteMeta(formatRuleValue(v))+"$")
+		}
+		if v, ok := g["contains"]; ok {
+			patterns = append(patterns, regexp.QuoteMeta(formatRuleValue(v)))
+		}
+		setPatterns(schema, patterns)
+
+		for rule, format := range schemaFormats {
+			if g.bool(rule) {
+				schema.Set("format", format)
+			}
+		}
+
+		set("const", "const")
+		set("enum", "in")
+		if v, ok := g["not_in"]; ok {
+			schema.Set("not", map[string]interface{}{"enum": v})
+		}
+
+	case "repeated":
+		set("minItems", "min_items")
+		set("maxItems", "max_items")
+		if g.bool("unique") {
+			schema.Set("uniqueItems", true)
+		}
+
+	case "map":
+		set("minProperties", "min_pairs")
+		set("maxProperties", "max_pairs")
+
+	case "enum":
+		names := func(numbers interface{}) []interface{} {
+			list, isList := numbers.([]interface{})
+			if !isList {
+				list = []interface{}{numbers}
+			}
+
+			var res []interface{}
+			for _, value := range enum.Values {
+				for _, number := range list {
+					if value.Number == formatRuleValue(number) {
+						res = append(res, value.Name)
+					}
+				}
+			}
+			return res
+		}
+
+		if v, ok := g["const"]; ok {
+			schema.Set("enum", names(v))
+		}
+		if v, ok := g["in"]; ok {
+			schema.Set("enum", names(v))
+		}
+		if v, ok := g["not_in"]; ok {
+			excluded := make(map[interface{}]bool)
+			for _, name := range names(v) {
+				excluded[name] = true
+			}
+
+			var allowed []interface{}
+			all, _ := schema.Get("enum")
+			for _, name := range all.([]interface{}) {
+				if !excluded[name] {
+					allowed = append(allowed, name)
+				}
+			}
+			schema.Set("enum", allowed)
+		}
+
+	case "int32", "sint32", "sfixed32", "uint32", "fixed32",
+		"int64", "sint64", "sfixed64", "uint64", "fixed64", "float", "double":
+		set("exclusiveMinimum", "gt")
+		set("minimum", "gte")
+		set("exclusiveMaximum", "lt")
+		set("maximum", "lte")
+		set("const", "const")
+		set("enum", "in")
+		if v, ok := g["not_in"]; ok {
+			schema.Set("not", map[string]interface{}{"enum": v})
+		}
+	}
+
+	return schema
+}
+
+// setPatterns sets a single pattern directly, several ones have to match all.
+func setPatterns(schema *OrderedObject, patterns []string) {
+	switch len(patterns) {
+	case 0:
+	case 1:
+		schema.Set("pattern", patterns[0])
+	default:
+		all := make([]interface{}, 0, len(patterns))
+		for _, pattern := range patterns {
+			all = append(all, map[string]interface{}{"pattern": pattern})
+		}
+		schema.Set("allOf", all)
+	}
+}
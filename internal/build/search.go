@@ -0,0 +1,123 @@
+package build
+
+import (
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// searchIndexFile is the search index of the html docs, searchScriptFile holds the same index
+// as a script, it is loaded by the search box since scripts load over file:// too.
+const (
+	searchIndexFile  = "search.json"
+	searchScriptFile = "search.js"
+)
+
+// Kinds of search entries.
+const (
+	SearchPackage   = "package"
+	SearchService   = "service"
+	SearchMethod    = "method"
+	SearchMessage   = "message"
+	SearchField     = "field"
+	SearchEnum      = "enum"
+	SearchEnumValue = "value"
+)
+
+// SearchEntry is an element of the docs found by the search box.
+type SearchEntry struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Parent is the full name of the service, message or enum the element is declared in
+	Parent      string `json:"parent,omitempty"`
+	Description string `json:"description,omitempty"`
+	// URL is the page of the element relative to the root of the docs
+	URL string `json:"url"`
+}
+
+// renderSearchIndex writes the search index of all elements to search.json and search.js.
+func (r *Renderer) renderSearchIndex(path, outputFile string) error {
+	bs, err := json.Marshal(r.tmpl.SearchIndex(outputFile))
+	if err != nil {
+		return err
+	}
+
+	err = r.writeFile(filepath.Join(path, searchIndexFile), append(bs, '\n'))
+	if err != nil {
+		return err
+	}
+
+	script := append([]byte("window.protoSearchIndex = "), bs...)
+	return r.writeFile(filepath.Join(path, searchScriptFile), append(script, ";\n"...))
+}
+
+func (r *Renderer) writeFile(name string, bs []byte) error {
+	fp, err := r.createFile(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = fp.Write(bs)
+	_ = fp.Close()
+
+	return err
+}
+
+// SearchIndex returns the packages, services, methods, messages, fields, enums and enum values
+// of all files, linking to the outputFile rendered for each file.
+func (tmpl *Template) SearchIndex(outputFile string) []*SearchEntry {
+	var entries []*SearchEntry
+
+	for _, file := range tmpl.Files {
+		page := path.Join(filepath.ToSlash(file.Dir), outputFile)
+
+		add := func(kind, name, parent, desc, anchor string) {
+			entry := &SearchEntry{
+				Kind:        kind,
+				Name:        name,
+				Parent:      parent,
+				Description: strings.Join(strings.Fields(desc), " "),
+				URL:         page,
+			}
+
+			if anchor != "" {
+				entry.URL += "#" + AnchorFilter(anchor)
+			}
+
+			entries = append(entries, entry)
+		}
+
+		add(SearchPackage, file.Package, "", file.Description, "")
+
+		for _, service := range file.Services {
+			add(SearchService, service.FullName, "", service.Description, service.FullName)
+
+			for _, method := range service.Methods {
+				add(SearchMethod, method.Name, service.FullName, method.Description, service.FullName)
+			}
+		}
+
+		for _, message := range file.Messages {
+			if message.Ismapentry {
+				continue
+			}
+
+			add(SearchMessage, message.FullName, "", message.Description, message.FullName)
+
+			for _, field := range message.Fields {
+				add(SearchField, field.Name, message.FullName, field.Description, message.FullName)
+			}
+		}
+
+		for _, enum := range file.Enums {
+			add(SearchEnum, enum.FullName, "", enum.Description, enum.FullName)
+
+			for _, value := range enum.Values {
+				add(SearchEnumValue, value.Name, enum.FullName, value.Description, enum.FullName)
+			}
+		}
+	}
+
+	return entries
+}
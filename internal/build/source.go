@@ -0,0 +1,58 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ParseProtoFiles compiles .proto source files and converts them into the template model.
+// Files are resolved relative to the import paths, the well-known types are always available.
+func (tmpl *Template) ParseProtoFiles(importPaths []string, filenames ...string) error {
+	names := make([]string, 0, len(filenames))
+	for _, filename := range filenames {
+		name, err := importName(importPaths, filename)
+		if err != nil {
+			return err
+		}
+
+		names = append(names, name)
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: importPaths,
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+
+	results, err := compiler.Compile(context.Background(), names...)
+	if err != nil {
+		return fmt.Errorf("compile proto files failure: %w", err)
+	}
+
+	files := make([]protoreflect.FileDescriptor, 0, len(results))
+	for _, result := range results {
+		files = append(files, result)
+	}
+
+	return tmpl.ParseDescriptors(files...)
+}
+
+// importName returns the name of filename relative to the first import path containing it.
+func importName(importPaths []string, filename string) (string, error) {
+	for _, importPath := range importPaths {
+		rel, err := filepath.Rel(importPath, filename)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		return filepath.ToSlash(rel), nil
+	}
+
+	return "", fmt.Errorf("file %s is not under any import path: %v", filename, importPaths)
+}
@@ -1,19 +1,44 @@
 package build
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Template TODO
 type Template struct {
 	Files   []*File        `json:"files"`
 	Scalars []*ScalarValue `json:"scalarValueTypes"`
+	// LegacyJSON builds json examples with proto field names and type names as values
+	// instead of following the proto3 JSON mapping
+	LegacyJSON bool `json:"-"`
+	// Types are json examples of types not defined in the parsed files, see RegisterType
+	Types map[string]interface{} `json:"-"`
+	// Examples are user supplied examples keyed by full name, see LoadExamples
+	Examples map[string]interface{} `json:"-"`
+	// JSONOrder is the key order of json examples, OrderDeclaration by default
+	JSONOrder string `json:"-"`
+	// Diagnostics are the problems found while parsing
+	Diagnostics []*Diagnostic `json:"-"`
+	// Cycles are the recursive message types, see findCycles
+	Cycles []*Cycle `json:"-"`
+
+	// dirs indexes Files by Dir while appending
+	dirs map[string]*File
+	// messages indexes the messages of all files by full name, see finishParse
+	messages map[string]*Message
+	// recursive marks the messages on cycles, their examples depend on the way from the root
+	recursive map[string]bool
+	// openAPI is the document built by OpenAPI
+	openAPI *OrderedObject
 }
 
 // ParseFiles TODO
@@ -50,32 +75,7 @@ type Object struct {
 }
 
 func (tmpl *Template) buildMessagesJsonString() {
-	objects := map[string]Object{
-		"google.protobuf.BoolValue":   {JSONObject: "bool"},
-		"google.protobuf.Int32Value":  {JSONObject: "int32"},
-		"google.protobuf.Int64Value":  {JSONObject: "int64"},
-		"google.protobuf.UInt32Value": {JSONObject: "uint32"},
-		"google.protobuf.UInt64Value": {JSONObject: "uint64"},
-		"google.protobuf.FloatValue":  {JSONObject: "float"},
-		"google.protobuf.DoubleValue": {JSONObject: "double"},
-		"google.protobuf.StringValue": {JSONObject: "string"},
-		"google.protobuf.BytesValue":  {JSONObject: "bytes"},
-		"google.protobuf.Duration": {JSONObject: map[string]interface{}{
-			"seconds": "int64",
-			"nanos":   "int32",
-		}},
-		"google.protobuf.Timestamp": {JSONObject: map[string]interface{}{
-			"seconds": "int64",
-			"nanos":   "int32",
-		}},
-		"google.protobuf.Value": {JSONObject: "any json object or any json array, same as interface{}"},
-		"google.protobuf.ListValue": {JSONObject: []interface{}{
-			"any json object or any json array, same as []interface{}",
-		}},
-		"google.protobuf.Struct": {JSONObject: map[string]interface{}{
-			"string": "any json object or any json array, same as map[string]interface{}",
-		}},
-	}
+	objects := tmpl.wellKnownObjects()
 
 	for _, file := range tmpl.Files {
 		for _, enum := range file.Enums {
@@ -93,26 +93,116 @@ func (tmpl *Template) buildMessagesJsonString() {
 		objects[scalar.ProtoType] = Object{ScalarValue: scalar}
 	}
 
+	for name, example := range tmpl.Types {
+		objects[name] = Object{JSONObject: example}
+	}
+
 	for _, file := range tmpl.Files {
 		for _, message := range file.Messages {
-			visited := make(map[string]int)
+			message.messages = tmpl.messages
+			for _, f := range message.Fields {
+				f.messageType = objects[f.FullType].Message
+				f.enumType = objects[f.FullType].Enum
+			}
+		}
+
+		for _, service := range file.Services {
+			for _, method := range service.Methods {
+				method.requestMessage = objects[method.RequestFullType].Message
+				method.responseMessage = objects[method.ResponseFullType].Message
+			}
+		}
+	}
+
+	tmpl.checkExamples(objects)
 
-			o, err := tmpl.fromMessage(objects, message, visited)
+	for _, file := range tmpl.Files {
+		for _, message := range file.Messages {
+			o, err := tmpl.fromMessage(objects, message, make(map[string]bool))
 			if err != nil {
-				log.Printf("encode msg to json failure, error: %v", err)
+				tmpl.errorf(message.Filename, message.FullName, "build json example failure: %v", err)
 				continue
 			}
 
-			message.JSONObject = o.(map[string]interface{})
+			message.JSONObject = o.(*OrderedObject)
+			for _, ref := range collectRefs(message.JSONObject) {
+				if m, ok := tmpl.messages[ref]; ok {
+					message.Refs = append(message.Refs, m)
+				}
+			}
 		}
 	}
 }
 
-func (tmpl *Template) fromEnum(_ *Enum) (interface{}, error) {
-	return "int64", nil
+func (tmpl *Template) fromEnum(enum *Enum) (interface{}, error) {
+	if tmpl.LegacyJSON {
+		return "int64", nil
+	}
+
+	// enums are represented by the name of the value
+	if len(enum.Values) > 0 {
+		return enum.Values[0].Name, nil
+	}
+
+	return 0, nil
+}
+
+// scalarJSON returns the proto3 JSON representation of a sample scalar value.
+func scalarJSON(protoType string) interface{} {
+	switch protoType {
+	case "double", "float":
+		return 1.5
+	case "int32", "sint32", "sfixed32", "uint32", "fixed32":
+		return 1
+	case "int64", "sint64", "sfixed64", "uint64", "fixed64":
+		// 64 bit integers are strings in JSON
+		return "1"
+	case "bool":
+		return true
+	case "string":
+		return "string"
+	case "bytes":
+		// bytes are base64 encoded
+		return base64.StdEncoding.EncodeToString([]byte("bytes"))
+	default:
+		return nil
+	}
+}
+
+// jsonName returns the json name of a field, i.e. the lowerCamelCase name protoc derives
+// from the field name unless json_name is set.
+func jsonName(field *MessageField) string {
+	if field.JSONName != "" {
+		return field.JSONName
+	}
+
+	var sb strings.Builder
+
+	upper := false
+	for _, c := range field.Name {
+		switch {
+		case c == '_':
+			upper = true
+		case upper:
+			sb.WriteRune(unicode.ToUpper(c))
+			upper = false
+		default:
+			sb.WriteRune(c)
+		}
+	}
+
+	return sb.String()
 }
 
 func (tmpl *Template) fromScalarValue(value *ScalarValue) (interface{}, error) {
+	if !tmpl.LegacyJSON {
+		if v := scalarJSON(value.ProtoType); v != nil {
+			return v, nil
+		}
+
+		return nil, fmt.Errorf("unknown scalar type: %s", value.ProtoType)
+	}
+
 	switch value.ProtoType {
 	case "float", "double":
 		fallthrough
@@ -129,19 +219,50 @@ func (tmpl *Template) fromScalarValue(value *ScalarValue) (interface{}, error) {
 	}
 }
 
-func (tmpl *Template) fromMessage(objects map[string]Object, value *Message, visited map[string]int) (interface{}, error) {
-	if _, ok := visited[value.FullName]; ok {
-		return map[string]interface{}{}, nil
+func (tmpl *Template) fromMessage(objects map[string]Object, value *Message, visited map[string]bool) (interface{}, error) {
+	if example, ok := value.Example.(*OrderedObject); ok {
+		return example, nil
+	}
+
+	if obj := objects[value.FullName]; obj.Message == value && obj.JSONObject != nil {
+		return obj.JSONObject, nil
+	}
+
+	// a message already on the way from the root would repeat forever, it is referenced instead
+	if visited[value.FullName] {
+		return map[string]interface{}{refKey: value.FullName}, nil
 	}
 
-	visited[value.FullName] = 1
+	visited[value.FullName] = true
 	defer func() {
 		delete(visited, value.FullName)
 	}()
 
-	res := make(map[string]interface{})
+	res := newOrderedObject()
+
+	// only one member of a oneof may be set, the first declared one is used as example
+	skipped := make(map[*MessageField]bool)
+	for _, oneof := range value.Oneofs() {
+		for _, f := range oneof.Fields[1:] {
+			skipped[f] = true
+		}
+	}
+
+	for _, f := range tmpl.orderedFields(value) {
+		if skipped[f] {
+			continue
+		}
+
+		name := f.Name
+		if !tmpl.LegacyJSON {
+			name = jsonName(f)
+		}
+
+		if f.Example != nil {
+			res.Set(name, f.Example)
+			continue
+		}
 
-	for _, f := range value.Fields {
 		v, err := tmpl.fromObjectName(objects, f.FullType, visited)
 		if err != nil {
 			return nil, err
@@ -149,24 +270,39 @@ func (tmpl *Template) fromMessage(objects map[string]Object, value *Message, vis
 
 		switch {
 		case f.Isarray:
-			res[f.Name] = []interface{}{v}
+			res.Set(name, tmpl.sampleList(objects, f, v))
 
 		case f.Ismap:
-			_, err := tmpl.fromObjectName(objects, f.KeyFullType, visited)
+			k, err := tmpl.fromObjectName(objects, f.KeyFullType, visited)
 			if err != nil {
 				return nil, fmt.Errorf("map key except scalar type, but %s", f.KeyFullType)
 			}
 
-			res[f.Name] = map[string]interface{}{"string": v}
+			key := "string"
+			if !tmpl.LegacyJSON {
+				// map keys are always strings in JSON
+				key = fmt.Sprint(tmpl.sampleField(objects, f, "map.keys.", f.KeyFullType, k))
+			}
+
+			res.Set(name, map[string]interface{}{key: tmpl.sampleField(objects, f, "map.values.", f.FullType, v)})
 		default:
-			res[f.Name] = v
+			res.Set(name, tmpl.sampleField(objects, f, "", f.FullType, v))
 		}
 	}
 
+	if tmpl.JSONOrder == OrderName {
+		sort.Strings(res.keys)
+	}
+
+	// the example of a message on no cycle is the same on every way to it
+	if !tmpl.recursive[value.FullName] {
+		objects[value.FullName] = Object{Message: value, JSONObject: res}
+	}
+
 	return res, nil
 }
 
-func (tmpl *Template) fromObjectName(objects map[string]Object, objectName string, visited map[string]int) (interface{}, error) {
+func (tmpl *Template) fromObjectName(objects map[string]Object, objectName string, visited map[string]bool) (interface{}, error) {
 	var err error
 
 	obj, ok := objects[objectName]
@@ -174,7 +310,8 @@ func (tmpl *Template) fromObjectName(objects map[string]Object, objectName strin
 		return nil, fmt.Errorf("unknown object: %s", objectName)
 	}
 
-	if obj.JSONObject != nil {
+	// well-known and registered types only carry their example, which may be null
+	if obj.JSONObject != nil || (obj.Enum == nil && obj.Message == nil && obj.ScalarValue == nil) {
 		return obj.JSONObject, nil
 	}
 
@@ -182,13 +319,18 @@ func (tmpl *Template) fromObjectName(objects map[string]Object, objectName strin
 	case obj.Enum != nil:
 		obj.JSONObject, err = tmpl.fromEnum(obj.Enum)
 	case obj.Message != nil:
-		obj.JSONObject, err = tmpl.fromMessage(objects, obj.Message, visited)
+		// messages are cached by fromMessage
+		return tmpl.fromMessage(objects, obj.Message, visited)
 	case obj.ScalarValue != nil:
 		obj.JSONObject, err = tmpl.fromScalarValue(obj.ScalarValue)
 	default:
 		return nil, fmt.Errorf("unknown error")
 	}
 
+	if err == nil {
+		objects[objectName] = obj
+	}
+
 	return obj.JSONObject, err
 }
 
@@ -216,18 +358,27 @@ func (tmpl *Template) appendFile(newer *File) error {
 		return nil
 	}
 
-	var file *File
-
 	newer.Dir = filepath.Clean(filepath.Dir(newer.Name))
 
-	for _, iter := range tmpl.Files {
-		if iter.Dir == newer.Dir {
-			file = iter
-			break
-		}
+	for _, enum := range newer.Enums {
+		enum.Filename = newer.Name
+	}
+
+	for _, message := range newer.Messages {
+		message.Filename = newer.Name
+	}
+
+	for _, service := range newer.Services {
+		service.Filename = newer.Name
 	}
 
-	if file == nil {
+	if tmpl.dirs == nil {
+		tmpl.dirs = make(map[string]*File)
+	}
+
+	file, ok := tmpl.dirs[newer.Dir]
+	if !ok {
+		tmpl.dirs[newer.Dir] = newer
 		tmpl.Files = append(tmpl.Files, newer)
 		return nil
 	}
@@ -278,6 +429,13 @@ func (tmpl *Template) appendScalarValue(scalarValue *ScalarValue) {
 }
 
 func (tmpl *Template) finishParse() {
+	tmpl.messages = make(map[string]*Message)
+	for _, file := range tmpl.Files {
+		for _, message := range file.Messages {
+			tmpl.messages[message.FullName] = message
+		}
+	}
+
 	for _, file := range tmpl.Files {
 		for _, enum := range file.Enums {
 			enum.File = file
@@ -291,7 +449,7 @@ func (tmpl *Template) finishParse() {
 			message.File = file
 
 			for _, field := range message.Fields {
-				tmpl.handleMapField(field)
+				tmpl.handleMapField(file, message, field)
 			}
 		}
 
@@ -304,10 +462,13 @@ func (tmpl *Template) finishParse() {
 		}
 	}
 
+	tmpl.parseExamples()
+	tmpl.resolveHTTPRules()
 	tmpl.sort()
+	tmpl.findCycles()
 }
 
-func (tmpl *Template) handleMapField(messageField *MessageField) {
+func (tmpl *Template) handleMapField(file *File, message *Message, messageField *MessageField) {
 
 	var mapEntry *Message
 
@@ -323,13 +484,15 @@ func (tmpl *Template) handleMapField(messageField *MessageField) {
 		return
 	}
 
-	for _, file := range tmpl.Files {
-		for _, message := range file.Messages {
-			if message.FullName == messageField.FullType {
-				mapEntry = message
-				break
-			}
-		}
+	mapEntry = tmpl.messages[messageField.FullType]
+	if mapEntry == nil {
+		tmpl.errorf(message.Filename, message.FullName+"."+messageField.Name, "map entry message %s not found", messageField.FullType)
+
+		messageField.Label = "array"
+		messageField.Isarray = true
+		messageField.Ismap = false
+		messageField.Done = true
+		return
 	}
 
 	var key, value *MessageField
@@ -417,12 +580,8 @@ type File struct {
 }
 
 // Option returns the named option.
-func (f File) Option(name string) *ValidatorExtension {
-	if f.Options != nil {
-		return f.Options[name]
-	} else {
-		return nil
-	}
+func (f File) Option(name string) interface{} {
+	return f.Options.Get(name)
 }
 
 // FileExtension contains details about top-level extensions within a proto(2) file.
@@ -445,28 +604,36 @@ type FileExtension struct {
 
 // Message TODO
 type Message struct {
-	File          *File                  `json:"-"`
-	Name          string                 `json:"name"`
-	LongName      string                 `json:"longName"`
-	FullName      string                 `json:"fullName"`
-	Description   string                 `json:"description"`
-	HasExtensions bool                   `json:"hasExtensions"`
-	HasFields     bool                   `json:"hasFields"`
-	HasOneofs     bool                   `json:"hasOneofs"`
-	Extensions    []*MessageExtension    `json:"extensions"`
-	Fields        []*MessageField        `json:"fields"`
-	Options       Options                `json:"options,omitempty"`
-	Ismapentry    bool                   `json:"-"`
-	JSONObject    map[string]interface{} `json:"-"`
+	File           *File               `json:"-"`
+	Filename       string              `json:"-"` // the proto file declaring the message, File may merge several
+	Name           string              `json:"name"`
+	LongName       string              `json:"longName"`
+	FullName       string              `json:"fullName"`
+	Description    string              `json:"description"`
+	HasExtensions  bool                `json:"hasExtensions"`
+	HasFields      bool                `json:"hasFields"`
+	HasOneofs      bool                `json:"hasOneofs"`
+	Extensions     []*MessageExtension `json:"extensions"`
+	Fields         []*MessageField     `json:"fields"`
+	Options        Options             `json:"options,omitempty"`
+	ReservedRanges []*ReservedRange    `json:"reservedRanges,omitempty"`
+	ReservedNames  []string            `json:"reservedNames,omitempty"`
+	Ismapentry     bool                `json:"-"`
+	JSONObject     *OrderedObject      `json:"-"`
+	// OneofOptions are the options of the oneofs keyed by name, e.g. buf.validate.oneof
+	OneofOptions map[string]Options `json:"-"`
+	// Refs are the messages referenced by $ref in the json example
+	Refs []*Message `json:"-"`
+	// Example is the user supplied example replacing the generated one, see parseExamples
+	Example interface{} `json:"-"`
+
+	// messages are all messages by full name, the packed messages of Any are resolved with them
+	messages map[string]*Message
 }
 
 // Option returns the named option.
-func (m Message) Option(name string) *ValidatorExtension {
-	if m.Options != nil {
-		return m.Options[name]
-	} else {
-		return nil
-	}
+func (m Message) Option(name string) interface{} {
+	return m.Options.Get(name)
 }
 
 func (m Message) deepJSONObject(obj interface{}, deep int) interface{} {
@@ -474,7 +641,7 @@ func (m Message) deepJSONObject(obj interface{}, deep int) interface{} {
 		switch obj.(type) {
 		case []interface{}:
 			return []interface{}{}
-		case map[string]interface{}:
+		case map[string]interface{}, *OrderedObject:
 			return map[string]interface{}{}
 		default:
 			return obj
@@ -494,6 +661,17 @@ func (m Message) deepJSONObject(obj interface{}, deep int) interface{} {
 			res[k] = m.deepJSONObject(v, deep-1)
 		}
 		return res
+	case *OrderedObject:
+		if x == nil {
+			return x
+		}
+
+		res := newOrderedObject()
+		for _, k := range x.Keys() {
+			v, _ := x.Get(k)
+			res.Set(k, m.deepJSONObject(v, deep-1))
+		}
+		return res
 	default:
 		return obj
 	}
@@ -506,6 +684,34 @@ func (m Message) JSONString(deep int) string {
 	return string(bs)
 }
 
+// Reserved returns the reserved field numbers and names of this message.
+func (m Message) Reserved() []string {
+	return reserved(m.ReservedRanges, m.ReservedNames)
+}
+
+// Oneofs returns the oneof groups of this message in declaration order.
+func (m Message) Oneofs() []*Oneof {
+	var res []*Oneof
+
+	index := make(map[string]*Oneof)
+	for _, field := range m.Fields {
+		if !field.Isoneof {
+			continue
+		}
+
+		oneof, ok := index[field.Oneofdecl]
+		if !ok {
+			oneof = &Oneof{Name: field.Oneofdecl, Options: m.OneofOptions[field.Oneofdecl]}
+			index[oneof.Name] = oneof
+			res = append(res, oneof)
+		}
+
+		oneof.Fields = append(oneof.Fields, field)
+	}
+
+	return res
+}
+
 // FieldOptions returns all options that are set on the fields in this message.
 func (m Message) FieldOptions() []string {
 	optionSet := make(map[string]struct{})
@@ -544,6 +750,8 @@ func (m Message) FieldsWithOption(optionName string) []*MessageField {
 type MessageField struct {
 	Message      *Message `json:"-"`
 	Name         string   `json:"name"`
+	Number       int      `json:"number,omitempty"`
+	JSONName     string   `json:"jsonName,omitempty"`
 	Description  string   `json:"description"`
 	Label        string   `json:"label"`
 	Type         string   `json:"type"`
@@ -559,15 +767,75 @@ type MessageField struct {
 	KeyType      string   `json:"-"`
 	KeyLongType  string   `json:"-"`
 	KeyFullType  string   `json:"-"`
+	// Example is the user supplied example of the field, see parseExamples
+	Example interface{} `json:"-"`
+	// messageType and enumType are the type of the field, or of the map values
+	messageType *Message
+	enumType    *Enum
 }
 
 // Option returns the named option.
-func (f MessageField) Option(name string) *ValidatorExtension {
-	if f.Options != nil {
-		return f.Options[name]
-	} else {
-		return nil
+func (f MessageField) Option(name string) interface{} {
+	return f.Options.Get(name)
+}
+
+// ReservedRange is an inclusive range of reserved field or enum value numbers.
+type ReservedRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Enum is set for ranges of enum values, their max is math.MaxInt32
+	Enum bool `json:"-"`
+}
+
+// String formats the range the way it is declared in proto files, e.g. "9 to 11".
+func (r ReservedRange) String() string {
+	max := maxFieldNumber
+	if r.Enum {
+		max = math.MaxInt32
+	}
+
+	switch {
+	case r.Start == r.End:
+		return strconv.Itoa(r.Start)
+	case r.End == max:
+		return fmt.Sprintf("%d to max", r.Start)
+	default:
+		return fmt.Sprintf("%d to %d", r.Start, r.End)
+	}
+}
+
+// maxFieldNumber is the largest valid field number, "max" in reserved ranges.
+const maxFieldNumber = 536870911
+
+func reserved(ranges []*ReservedRange, names []string) []string {
+	res := make([]string, 0, len(ranges)+len(names))
+	for _, r := range ranges {
+		res = append(res, r.String())
 	}
+
+	return append(res, names...)
+}
+
+// Oneof is a group of fields of which at most one is set.
+type Oneof struct {
+	Name    string
+	Fields  []*MessageField
+	Options Options
+}
+
+// FieldNames returns the names of the members.
+func (o Oneof) FieldNames() []string {
+	names := make([]string, 0, len(o.Fields))
+	for _, field := range o.Fields {
+		names = append(names, field.Name)
+	}
+
+	return names
+}
+
+// Example returns the member used in json examples.
+func (o Oneof) Example() *MessageField {
+	return o.Fields[0]
 }
 
 // MessageExtension contains details about message-scoped extensions in proto(2) files.
@@ -581,22 +849,26 @@ type MessageExtension struct {
 
 // Enum TODO
 type Enum struct {
-	File        *File        `json:"-"`
-	Name        string       `json:"name"`
-	LongName    string       `json:"longName"`
-	FullName    string       `json:"fullName"`
-	Description string       `json:"description"`
-	Values      []*EnumValue `json:"values"`
-	Options     Options      `json:"options,omitempty"`
+	File           *File            `json:"-"`
+	Filename       string           `json:"-"` // the proto file declaring the enum, File may merge several
+	Name           string           `json:"name"`
+	LongName       string           `json:"longName"`
+	FullName       string           `json:"fullName"`
+	Description    string           `json:"description"`
+	Values         []*EnumValue     `json:"values"`
+	Options        Options          `json:"options,omitempty"`
+	ReservedRanges []*ReservedRange `json:"reservedRanges,omitempty"`
+	ReservedNames  []string         `json:"reservedNames,omitempty"`
 }
 
 // Option returns the named option.
-func (e Enum) Option(name string) *ValidatorExtension {
-	if e.Options != nil {
-		return e.Options[name]
-	} else {
-		return nil
-	}
+func (e Enum) Option(name string) interface{} {
+	return e.Options.Get(name)
+}
+
+// Reserved returns the reserved value numbers and names of this enum.
+func (e Enum) Reserved() []string {
+	return reserved(e.ReservedRanges, e.ReservedNames)
 }
 
 // ValueOptions returns all options that are set on the values in this enum.
@@ -642,17 +914,14 @@ type EnumValue struct {
 }
 
 // Option returns the named option.
-func (v EnumValue) Option(name string) *ValidatorExtension {
-	if v.Options != nil {
-		return v.Options[name]
-	} else {
-		return nil
-	}
+func (v EnumValue) Option(name string) interface{} {
+	return v.Options.Get(name)
 }
 
 // Service TODO
 type Service struct {
 	File        *File            `json:"-"`
+	Filename    string           `json:"-"` // the proto file declaring the service, File may merge several
 	Name        string           `json:"name"`
 	LongName    string           `json:"longName"`
 	FullName    string           `json:"fullName"`
@@ -662,12 +931,8 @@ type Service struct {
 }
 
 // Option returns the named option.
-func (s Service) Option(name string) *ValidatorExtension {
-	if s.Options != nil {
-		return s.Options[name]
-	} else {
-		return nil
-	}
+func (s Service) Option(name string) interface{} {
+	return s.Options.Get(name)
 }
 
 // MethodOptions returns all options that are set on the methods in this service.
@@ -706,31 +971,36 @@ func (s Service) MethodsWithOption(optionName string) []*ServiceMethod {
 
 // ServiceMethod TODO
 type ServiceMethod struct {
-	Service           *Service `json:"-"`
-	Name              string   `json:"name"`
-	Description       string   `json:"description"`
-	RequestType       string   `json:"requestType"`
-	RequestLongType   string   `json:"requestLongType"`
-	RequestFullType   string   `json:"requestFullType"`
-	RequestStreaming  bool     `json:"requestStreaming"`
-	ResponseType      string   `json:"responseType"`
-	ResponseLongType  string   `json:"responseLongType"`
-	ResponseFullType  string   `json:"responseFullType"`
-	ResponseStreaming bool     `json:"responseStreaming"`
-	Options           Options  `json:"options,omitempty"`
+	Service           *Service    `json:"-"`
+	Name              string      `json:"name"`
+	Description       string      `json:"description"`
+	RequestType       string      `json:"requestType"`
+	RequestLongType   string      `json:"requestLongType"`
+	RequestFullType   string      `json:"requestFullType"`
+	RequestStreaming  bool        `json:"requestStreaming"`
+	ResponseType      string      `json:"responseType"`
+	ResponseLongType  string      `json:"responseLongType"`
+	ResponseFullType  string      `json:"responseFullType"`
+	ResponseStreaming bool        `json:"responseStreaming"`
+	Options           Options     `json:"options,omitempty"`
+	HTTPRules         []*HTTPRule `json:"-"`
+	// RequestExample and ResponseExample are user supplied examples, see parseExamples
+	RequestExample  interface{} `json:"-"`
+	ResponseExample interface{} `json:"-"`
+	requestMessage  *Message
+	responseMessage *Message
 }
 
 // Option returns the named option.
-func (m ServiceMethod) Option(name string) *ValidatorExtension {
-	if m.Options != nil {
-		return m.Options[name]
-	} else {
-		return nil
-	}
+func (m ServiceMethod) Option(name string) interface{} {
+	return m.Options.Get(name)
 }
 
-// Options TODO
-type Options map[string]*ValidatorExtension
+// Options holds all options set on an element. Standard options are keyed by their field
+// name, e.g. "deprecated" or "go_package", custom options by the full name of the extension,
+// e.g. "validate.rules". Values are bool, numbers, strings, []interface{} for repeated and
+// map[string]interface{} for message options, validate.rules is kept as *ValidatorExtension.
+type Options map[string]interface{}
 
 // UnmarshalJSON TODO
 func (o *Options) UnmarshalJSON(b []byte) error {
@@ -742,7 +1012,7 @@ func (o *Options) UnmarshalJSON(b []byte) error {
 	}
 
 	if *o == nil {
-		*o = make(map[string]*ValidatorExtension)
+		*o = make(Options)
 	}
 
 	for k, v := range in {
@@ -751,11 +1021,92 @@ func (o *Options) UnmarshalJSON(b []byte) error {
 			var extension ValidatorExtension
 			bs, _ := json.Marshal(v)
 			err = json.Unmarshal(bs, &extension.rules)
-			(*o)["validate.rules"] = &extension
+			if err != nil {
+				return fmt.Errorf("decode option %s failure: %w", k, err)
+			}
+
+			(*o)[k] = &extension
+		case "buf.validate.field", "buf.validate.message", "buf.validate.oneof":
+			rules, _ := v.(map[string]interface{})
+			(*o)[k] = &ValidatorExtension{
+				rules: flattenJSONRules("", rules),
+			}
+		default:
+			(*o)[k] = v
+		}
+	}
+
+	return nil
+}
+
+// Names returns the sorted names of all options.
+func (o Options) Names() []string {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether the named option is set.
+func (o Options) Has(name string) bool {
+	_, ok := o[name]
+	return ok
+}
+
+// Get returns the value of the named option, or nil if it is not set.
+func (o Options) Get(name string) interface{} {
+	return o[name]
+}
+
+// Bool returns the named option as bool, e.g. {{if .Options.Bool "deprecated"}}.
+func (o Options) Bool(name string) bool {
+	v, _ := o[name].(bool)
+	return v
+}
+
+// String returns the named option formatted as string, or "" if it is not set.
+func (o Options) String(name string) string {
+	v, ok := o[name]
+	if !ok || v == nil {
+		return ""
+	}
+
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(v)
+}
+
+// validatorOptions are the options holding protoc-gen-validate and protovalidate rules.
+var validatorOptions = []string{
+	"validate.rules",
+	"buf.validate.field",
+	"buf.validate.message",
+	"buf.validate.oneof",
+}
+
+// Validator returns the protoc-gen-validate and protovalidate rules, or nil if there are none.
+func (o Options) Validator() *ValidatorExtension {
+	var res *ValidatorExtension
+
+	for _, name := range validatorOptions {
+		v, ok := o[name].(*ValidatorExtension)
+		switch {
+		case !ok:
+		case res == nil:
+			res = v
+		default:
+			res = &ValidatorExtension{
+				rules: append(append([]ValidatorRule(nil), res.rules...), v.rules...),
+			}
 		}
 	}
 
-	return err
+	return res
 }
 
 // ValidatorRule TODO
@@ -766,7 +1117,7 @@ type ValidatorRule struct {
 
 // ValidatorExtension TODO
 type ValidatorExtension struct {
-	rules []ValidatorRule `json:"validate.rules"`
+	rules []ValidatorRule
 }
 
 // Rules TODO
@@ -774,6 +1125,13 @@ func (v ValidatorExtension) Rules() []ValidatorRule {
 	return v.rules
 }
 
+// CELRule is a protovalidate rule expressed in CEL.
+type CELRule struct {
+	ID         string `json:"id"`
+	Message    string `json:"message"`
+	Expression string `json:"expression"`
+}
+
 // ScalarValue contains information about scalar value types in protobuf. The common use case for this type is to know
 // which language specific type maps to the protobuf type.
 //
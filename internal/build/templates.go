@@ -0,0 +1,185 @@
+package build
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	htmlTemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template/parse"
+
+	"github.com/Masterminds/sprig"
+)
+
+//go:embed tmpl
+var templateFS embed.FS
+
+// checkTemplatesDir reports a templates dir which cannot be used.
+func checkTemplatesDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("templates dir failure, path: %s, err: %w", dir, err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("templates dir failure, path: %s, err: not a dir", dir)
+	}
+
+	return nil
+}
+
+// readTemplate reads a template file of the templates dir, or the embedded one if the dir
+// does not have it.
+func (r *Renderer) readTemplate(name string) ([]byte, error) {
+	if r.templates != "" {
+		filename := filepath.Join(r.templates, name)
+
+		bs, err := os.ReadFile(filename)
+		if err == nil {
+			return bs, nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("read template failure, path: %s, err: %w", filename, err)
+		}
+	}
+
+	bs, err := templateFS.ReadFile(path.Join("tmpl", name))
+	if err != nil {
+		if r.templates != "" {
+			return nil, fmt.Errorf("template %s not found in %s nor embedded: %w", name, r.templates, fs.ErrNotExist)
+		}
+
+		return nil, fmt.Errorf("template %s not embedded: %w", name, fs.ErrNotExist)
+	}
+
+	return bs, nil
+}
+
+// partials returns the partial templates of the templates dir for a format, they are named
+// with a leading underscore and the suffix of the format, e.g. "_fields.md.tmpl".
+func (r *Renderer) partials(suffix string) ([]string, error) {
+	if r.templates == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(r.templates)
+	if err != nil {
+		return nil, fmt.Errorf("read templates dir failure, path: %s, err: %w", r.templates, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), "_") && strings.HasSuffix(entry.Name(), suffix) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// parseTemplate parses the template files, the first one is executed. The partials of the
+// same format are parsed last, so their {{define}} blocks override the ones of the templates.
+// Each partial is parsed into a template of its own name, text outside of its {{define}}
+// blocks never replaces the executed template.
+func (r *Renderer) parseTemplate(name string, templateFiles ...string) (*htmlTemplate.Template, error) {
+	template := htmlTemplate.New(name).Funcs(funcMap).Funcs(sprig.HtmlFuncMap()).Funcs(htmlTemplate.FuncMap{"t": r.translate})
+
+	// the format is the second extension of the first template, e.g. "md" of "proto.doc.md.tmpl"
+	suffix := filepath.Ext(strings.TrimSuffix(templateFiles[0], ".tmpl")) + ".tmpl"
+
+	partials, err := r.partials(suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, templateFile := range append(templateFiles, partials...) {
+		templateText, err := r.readTemplate(templateFile)
+		if err != nil {
+			return nil, err
+		}
+
+		t := template
+		if strings.HasPrefix(templateFile, "_") {
+			t = template.New(templateFile)
+		}
+
+		_, err = t.Parse(string(templateText))
+		if err != nil {
+			return nil, fmt.Errorf("parse template failure, path: %s, err: %w", templateFile, err)
+		}
+	}
+
+	err = checkTemplateRefs(template)
+	if err != nil {
+		return nil, fmt.Errorf("parse template failure, path: %s, err: %w", templateFiles[0], err)
+	}
+
+	return template, nil
+}
+
+// checkTemplateRefs reports the templates invoked by {{template "name"}} which are not defined,
+// instead of failing while executing. Only the templates reachable from the executed one are
+// checked, partials may define blocks of other templates.
+func checkTemplateRefs(template *htmlTemplate.Template) error {
+	checked := map[string]bool{template.Name(): true}
+	queue := []*htmlTemplate.Template{template}
+
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+
+		if t.Tree == nil {
+			continue
+		}
+
+		var err error
+
+		var walk func(node parse.Node)
+		walk = func(node parse.Node) {
+			switch x := node.(type) {
+			case *parse.ListNode:
+				if x == nil {
+					return
+				}
+				for _, n := range x.Nodes {
+					walk(n)
+				}
+			case *parse.IfNode:
+				walk(x.List)
+				walk(x.ElseList)
+			case *parse.RangeNode:
+				walk(x.List)
+				walk(x.ElseList)
+			case *parse.WithNode:
+				walk(x.List)
+				walk(x.ElseList)
+			case *parse.TemplateNode:
+				used := template.Lookup(x.Name)
+				if used == nil || used.Tree == nil {
+					if err == nil {
+						err = fmt.Errorf("template %q used by %q is not defined", x.Name, t.Name())
+					}
+					return
+				}
+
+				if !checked[x.Name] {
+					checked[x.Name] = true
+					queue = append(queue, used)
+				}
+			}
+		}
+
+		walk(t.Tree.Root)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPartialOverridesMessage(t *testing.T) {
+	protoDir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(protoDir, "api"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(protoDir, "api", "user.proto")
+	err = os.WriteFile(filename, []byte(`syntax = "proto3";
+package api;
+
+message User {
+  string id = 1;
+}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templatesDir := t.TempDir()
+	err = os.WriteFile(filepath.Join(templatesDir, "_msg.md.tmpl"), []byte(
+		`note: text outside of define blocks is not rendered
+{{define "message"}}{{with .Message}}CUSTOM {{.LongName}}
+{{range .Fields}}{{template "field-row" .}}
+{{end}}{{end}}{{end}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tmpl Template
+	err = tmpl.ParseProtoFiles([]string{protoDir}, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := new(memoryOutput)
+	r := &Renderer{tmpl: &tmpl, output: output, templates: templatesDir}
+
+	err = r.Render("out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc, toc string
+	for _, file := range output.files {
+		switch file.name {
+		case filepath.Join("out", "api", "proto.md"):
+			doc = file.String()
+		case filepath.Join("out", "proto.md"):
+			toc = file.String()
+		}
+	}
+
+	if !strings.Contains(doc, "CUSTOM User") || !strings.Contains(doc, "| id |") {
+		t.Fatalf("message block not overridden:\n%s", doc)
+	}
+
+	if strings.Contains(doc, "note:") || strings.Contains(toc, "note:") || !strings.Contains(toc, "api") {
+		t.Fatalf("partial text rendered instead of the template:\n%s\n%s", toc, doc)
+	}
+}
@@ -0,0 +1,405 @@
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ruleGroup holds the validate rules of one rule type, e.g. "string", keyed by rule name
+// without the type prefix, e.g. "min_len" or "items.string.min_len".
+type ruleGroup map[string]interface{}
+
+// Constraints describes the rules in readable text, e.g. ["required", "1–64 chars",
+// "must match ^[a-z]+$"].
+func (v ValidatorExtension) Constraints() []string {
+	return describeRules(v.rules)
+}
+
+func describeRules(rules []ValidatorRule) []string {
+	kinds, groups := groupRules(rules)
+
+	// "required" reads best first, CEL rules with their messages last
+	priority := map[string]int{"required": -1, "cel": 1}
+	sort.SliceStable(kinds, func(i, j int) bool {
+		return priority[kinds[i]] < priority[kinds[j]]
+	})
+
+	var res []string
+	for _, kind := range kinds {
+		res = append(res, groups[kind].describe(kind)...)
+	}
+
+	return res
+}
+
+// groupRules groups rules by their type, the kinds are returned in order of appearance.
+func groupRules(rules []ValidatorRule) ([]string, map[string]ruleGroup) {
+	var kinds []string
+
+	groups := make(map[string]ruleGroup)
+	for _, rule := range rules {
+		kind, name, _ := strings.Cut(rule.Name, ".")
+
+		group, ok := groups[kind]
+		if !ok {
+			group = make(ruleGroup)
+			groups[kind] = group
+			kinds = append(kinds, kind)
+		}
+
+		if name == "" {
+			name = kind
+		}
+
+		group[name] = rule.Value
+	}
+
+	return kinds, groups
+}
+
+// flattenJSONRules is the counterpart of flattenRules for rules decoded from json.
+func flattenJSONRules(prefix string, in map[string]interface{}) []ValidatorRule {
+	names := make([]string, 0, len(in))
+	for name := range in {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var rules []ValidatorRule
+	for _, name := range names {
+		v := in[name]
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		x, ok := v.(map[string]interface{})
+		switch {
+		case ok && len(x) == 0:
+			rules = append(rules, ValidatorRule{Name: name, Value: true})
+		case ok && !isJSONDuration(x):
+			rules = append(rules, flattenJSONRules(name, x)...)
+		default:
+			rules = append(rules, ValidatorRule{Name: name, Value: v})
+		}
+	}
+
+	return rules
+}
+
+func isJSONDuration(v map[string]interface{}) bool {
+	_, ok := v["seconds"]
+	return ok
+}
+
+// celRules reads the list value of a "cel" rule.
+func celRules(v interface{}) []CELRule {
+	list, _ := v.([]interface{})
+
+	res := make([]CELRule, 0, len(list))
+	for _, item := range list {
+		x, _ := item.(map[string]interface{})
+		res = append(res, CELRule{
+			ID:         stringValue(x["id"]),
+			Message:    stringValue(x["message"]),
+			Expression: stringValue(x["expression"]),
+		})
+	}
+
+	return res
+}
+
+func (g ruleGroup) describe(kind string) []string {
+	var res []string
+
+	if g.bool("required") {
+		res = append(res, "required")
+	}
+
+	switch kind {
+	case "cel":
+		for _, rule := range celRules(g["cel"]) {
+			res = append(res, rule.String())
+		}
+
+	case "ignore":
+		switch v := formatRuleValue(g["ignore"]); v {
+		case "IGNORE_ALWAYS":
+			res = append(res, "not validated")
+		case "IGNORE_UNSPECIFIED":
+		default:
+			res = append(res, "skipped when empty")
+		}
+
+	case "disabled":
+		if g.bool("disabled") {
+			res = append(res, "not validated")
+		}
+
+	case "oneof":
+		list, _ := g["oneof"].([]interface{})
+		for _, item := range list {
+			x, _ := item.(map[string]interface{})
+			text := "at most one of "
+			if required, _ := x["required"].(bool); required {
+				text = "exactly one of "
+			}
+			res = append(res, text+strings.Trim(formatRuleValue(x["fields"]), "[]"))
+		}
+
+	case "message":
+		if g.bool("skip") {
+			res = append(res, "not validated")
+		}
+
+	case "string":
+		res = append(res, g.length("len", "min_len", "max_len", "chars")...)
+		res = append(res, g.length("len_bytes", "min_bytes", "max_bytes", "bytes")...)
+		res = append(res, g.formats()...)
+		res = append(res, g.text()...)
+
+	case "float", "double":
+		if g.bool("finite") {
+			res = append(res, "finite")
+		}
+
+	case "bytes":
+		res = append(res, g.length("len", "min_len", "max_len", "bytes")...)
+		res = append(res, g.formats()...)
+		res = append(res, g.text()...)
+
+	case "enum":
+		if g.bool("defined_only") {
+			res = append(res, "defined values only")
+		}
+
+	case "repeated":
+		res = append(res, g.length("", "min_items", "max_items", "items")...)
+		if g.bool("unique") {
+			res = append(res, "unique items")
+		}
+		res = append(res, g.nested("items", "items")...)
+
+	case "map":
+		res = append(res, g.length("", "min_pairs", "max_pairs", "pairs")...)
+		if g.bool("no_sparse") {
+			res = append(res, "no sparse values")
+		}
+		res = append(res, g.nested("keys", "keys")...)
+		res = append(res, g.nested("values", "values")...)
+
+	case "timestamp":
+		if g.bool("lt_now") {
+			res = append(res, "before now")
+		}
+		if g.bool("gt_now") {
+			res = append(res, "after now")
+		}
+		if within, ok := g["within"]; ok {
+			res = append(res, fmt.Sprintf("within %s of now", formatRuleValue(within)))
+		}
+	}
+
+	res = append(res, g.bounds()...)
+
+	if v, ok := g["const"]; ok {
+		res = append(res, fmt.Sprintf("must be %s", formatRuleValue(v)))
+	}
+
+	if v, ok := g["in"]; ok {
+		res = append(res, fmt.Sprintf("one of %s", formatRuleValue(v)))
+	}
+
+	if v, ok := g["not_in"]; ok {
+		res = append(res, fmt.Sprintf("not one of %s", formatRuleValue(v)))
+	}
+
+	if g.bool("ignore_empty") {
+		res = append(res, "skipped when empty")
+	}
+
+	return res
+}
+
+// length describes exact, min and max length rules, e.g. "1–64 chars".
+func (g ruleGroup) length(exact, min, max, unit string) []string {
+	if v, ok := g[exact]; ok && exact != "" {
+		return []string{fmt.Sprintf("exactly %s %s", formatRuleValue(v), unit)}
+	}
+
+	lo, hasLo := g[min]
+	hi, hasHi := g[max]
+
+	switch {
+	case hasLo && hasHi:
+		return []string{fmt.Sprintf("%s–%s %s", formatRuleValue(lo), formatRuleValue(hi), unit)}
+	case hasLo:
+		return []string{fmt.Sprintf("at least %s %s", formatRuleValue(lo), unit)}
+	case hasHi:
+		return []string{fmt.Sprintf("at most %s %s", formatRuleValue(hi), unit)}
+	default:
+		return nil
+	}
+}
+
+// bounds describes gt, gte, lt and lte rules, e.g. "1 ≤ value ≤ 100".
+func (g ruleGroup) bounds() []string {
+	var lower, lowerOp, upper, upperOp string
+
+	if v, ok := g["gt"]; ok {
+		lower, lowerOp = formatRuleValue(v), "<"
+	} else if v, ok := g["gte"]; ok {
+		lower, lowerOp = formatRuleValue(v), "≤"
+	}
+
+	if v, ok := g["lt"]; ok {
+		upper, upperOp = formatRuleValue(v), "<"
+	} else if v, ok := g["lte"]; ok {
+		upper, upperOp = formatRuleValue(v), "≤"
+	}
+
+	switch {
+	case lowerOp != "" && upperOp != "":
+		return []string{fmt.Sprintf("%s %s value %s %s", lower, lowerOp, upperOp, upper)}
+	case lowerOp == "<":
+		return []string{"value > " + lower}
+	case lowerOp == "≤":
+		return []string{"value ≥ " + lower}
+	case upperOp != "":
+		return []string{fmt.Sprintf("value %s %s", upperOp, upper)}
+	default:
+		return nil
+	}
+}
+
+// ruleFormats are the well known string formats with their description and a sample value.
+var ruleFormats = []struct {
+	name   string
+	text   string
+	sample string
+}{
+	{"email", "email", "user@example.com"},
+	{"hostname", "hostname", "example.com"},
+	{"address", "hostname or IP address", "example.com"},
+	{"ip", "IP address", "192.168.0.1"},
+	{"ipv4", "IPv4 address", "192.168.0.1"},
+	{"ipv6", "IPv6 address", "2001:db8::1"},
+	{"uri", "URI", "https://example.com/path"},
+	{"uri_ref", "URI reference", "/path"},
+	{"uuid", "UUID", "123e4567-e89b-12d3-a456-426614174000"},
+	{"tuuid", "UUID without dashes", "123e4567e89b12d3a456426614174000"},
+	{"ulid", "ULID", "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+	{"host_and_port", "host and port", "example.com:8080"},
+	{"ip_with_prefixlen", "IP address with prefix length", "192.168.0.1/24"},
+	{"ip_prefix", "IP prefix", "192.168.0.0/24"},
+}
+
+func (g ruleGroup) formats() []string {
+	var res []string
+
+	for _, format := range ruleFormats {
+		if g.bool(format.name) {
+			res = append(res, format.text)
+		}
+	}
+
+	if v, ok := g["well_known_regex"]; ok {
+		res = append(res, strings.ToLower(strings.ReplaceAll(formatRuleValue(v), "_", " ")))
+	}
+
+	return res
+}
+
+func (g ruleGroup) text() []string {
+	var res []string
+
+	if v, ok := g["pattern"]; ok {
+		res = append(res, fmt.Sprintf("must match %s", formatRuleValue(v)))
+	}
+
+	if v, ok := g["prefix"]; ok {
+		res = append(res, fmt.Sprintf("must start with %s", formatRuleValue(v)))
+	}
+
+	if v, ok := g["suffix"]; ok {
+		res = append(res, fmt.Sprintf("must end with %s", formatRuleValue(v)))
+	}
+
+	if v, ok := g["contains"]; ok {
+		res = append(res, fmt.Sprintf("must contain %s", formatRuleValue(v)))
+	}
+
+	if v, ok := g["not_contains"]; ok {
+		res = append(res, fmt.Sprintf("must not contain %s", formatRuleValue(v)))
+	}
+
+	return res
+}
+
+// nested describes the rules of repeated items, map keys or map values, e.g.
+// "items: at least 2 chars".
+func (g ruleGroup) nested(name, label string) []string {
+	var names []string
+	for k := range g {
+		if strings.HasPrefix(k, name+".") {
+			names = append(names, k)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+
+	rules := make([]ValidatorRule, 0, len(names))
+	for _, k := range names {
+		rules = append(rules, ValidatorRule{Name: strings.TrimPrefix(k, name+"."), Value: g[k]})
+	}
+
+	desc := describeRules(rules)
+	if len(desc) == 0 {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("%s: %s", label, strings.Join(desc, ", "))}
+}
+
+// String formats the rule for docs, e.g. "order.dates: end must be after start".
+func (r CELRule) String() string {
+	switch {
+	case r.ID != "" && r.Message != "":
+		return fmt.Sprintf("%s: %s", r.ID, r.Message)
+	case r.Message != "":
+		return r.Message
+	case r.ID != "":
+		return fmt.Sprintf("%s: %s", r.ID, r.Expression)
+	default:
+		return r.Expression
+	}
+}
+
+func (g ruleGroup) bool(name string) bool {
+	v, _ := g[name].(bool)
+	return v
+}
+
+func formatRuleValue(v interface{}) string {
+	switch x := v.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(x))
+		for _, item := range x {
+			values = append(values, formatRuleValue(item))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case map[string]interface{}:
+		// durations and timestamps in the protoc-gen-doc json
+		if seconds, ok := x["seconds"]; ok {
+			return fmt.Sprintf("%vs", seconds)
+		}
+		return fmt.Sprint(x)
+	default:
+		return fmt.Sprint(v)
+	}
+}
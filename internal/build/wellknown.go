@@ -0,0 +1,180 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// wellKnownType holds the json examples of a type which is usually imported rather than
+// documented, in proto3 JSON mapping and in legacy mode.
+type wellKnownType struct {
+	JSON   interface{}
+	Legacy interface{}
+}
+
+// wellKnownTypes are the builtin types, see
+// https://protobuf.dev/reference/protobuf/google.protobuf/ and
+// https://github.com/googleapis/googleapis/tree/master/google/type
+var wellKnownTypes = map[string]wellKnownType{
+	// wrappers are represented by the wrapped value
+	"google.protobuf.BoolValue":   {JSON: scalarJSON("bool"), Legacy: "bool"},
+	"google.protobuf.Int32Value":  {JSON: scalarJSON("int32"), Legacy: "int32"},
+	"google.protobuf.Int64Value":  {JSON: scalarJSON("int64"), Legacy: "int64"},
+	"google.protobuf.UInt32Value": {JSON: scalarJSON("uint32"), Legacy: "uint32"},
+	"google.protobuf.UInt64Value": {JSON: scalarJSON("uint64"), Legacy: "uint64"},
+	"google.protobuf.FloatValue":  {JSON: scalarJSON("float"), Legacy: "float"},
+	"google.protobuf.DoubleValue": {JSON: scalarJSON("double"), Legacy: "double"},
+	"google.protobuf.StringValue": {JSON: scalarJSON("string"), Legacy: "string"},
+	"google.protobuf.BytesValue":  {JSON: scalarJSON("bytes"), Legacy: "bytes"},
+	"google.protobuf.Duration": {
+		JSON:   formatDuration(1, 500000000),
+		Legacy: map[string]interface{}{"seconds": "int64", "nanos": "int32"},
+	},
+	"google.protobuf.Timestamp": {
+		JSON:   sampleTime.Format(time.RFC3339),
+		Legacy: map[string]interface{}{"seconds": "int64", "nanos": "int32"},
+	},
+	"google.protobuf.Value": {
+		JSON:   "any json value",
+		Legacy: "any json object or any json array, same as interface{}",
+	},
+	"google.protobuf.ListValue": {
+		JSON:   []interface{}{"any json value"},
+		Legacy: []interface{}{"any json object or any json array, same as []interface{}"},
+	},
+	"google.protobuf.Struct": {
+		JSON:   map[string]interface{}{"key": "any json value"},
+		Legacy: map[string]interface{}{"string": "any json object or any json array, same as map[string]interface{}"},
+	},
+	"google.protobuf.NullValue": {
+		JSON:   nil,
+		Legacy: "int64",
+	},
+	"google.protobuf.Empty": {
+		JSON:   map[string]interface{}{},
+		Legacy: map[string]interface{}{},
+	},
+	"google.protobuf.FieldMask": {
+		// paths are joined by comma and use the json names
+		JSON:   "displayName,address.city",
+		Legacy: map[string]interface{}{"paths": []interface{}{"string"}},
+	},
+	"google.protobuf.Any": {
+		// the packed message is embedded next to @type, well-known types use "value"
+		JSON: map[string]interface{}{
+			"@type": "type.googleapis.com/google.protobuf.StringValue",
+			"value": scalarJSON("string"),
+		},
+		Legacy: map[string]interface{}{"type_url": "string", "value": "bytes"},
+	},
+
+	"google.type.Date": {
+		JSON:   map[string]interface{}{"year": 2024, "month": 1, "day": 1},
+		Legacy: map[string]interface{}{"year": "int32", "month": "int32", "day": "int32"},
+	},
+	"google.type.TimeOfDay": {
+		JSON:   map[string]interface{}{"hours": 8, "minutes": 30, "seconds": 0, "nanos": 0},
+		Legacy: map[string]interface{}{"hours": "int32", "minutes": "int32", "seconds": "int32", "nanos": "int32"},
+	},
+	"google.type.DayOfWeek": {
+		JSON:   "MONDAY",
+		Legacy: "int64",
+	},
+	"google.type.Month": {
+		JSON:   "JANUARY",
+		Legacy: "int64",
+	},
+	"google.type.Money": {
+		JSON:   map[string]interface{}{"currencyCode": "USD", "units": "1", "nanos": 500000000},
+		Legacy: map[string]interface{}{"currency_code": "string", "units": "int64", "nanos": "int32"},
+	},
+	"google.type.LatLng": {
+		JSON:   map[string]interface{}{"latitude": 37.422, "longitude": -122.084},
+		Legacy: map[string]interface{}{"latitude": "double", "longitude": "double"},
+	},
+	"google.type.Decimal": {
+		JSON:   map[string]interface{}{"value": "1.5"},
+		Legacy: map[string]interface{}{"value": "string"},
+	},
+	"google.type.Fraction": {
+		JSON:   map[string]interface{}{"numerator": "1", "denominator": "2"},
+		Legacy: map[string]interface{}{"numerator": "int64", "denominator": "int64"},
+	},
+	"google.type.Interval": {
+		JSON: map[string]interface{}{
+			"startTime": sampleTime.Format(time.RFC3339),
+			"endTime":   sampleTime.Add(time.Hour).Format(time.RFC3339),
+		},
+		Legacy: map[string]interface{}{
+			"start_time": map[string]interface{}{"seconds": "int64", "nanos": "int32"},
+			"end_time":   map[string]interface{}{"seconds": "int64", "nanos": "int32"},
+		},
+	},
+	"google.type.PhoneNumber": {
+		JSON:   map[string]interface{}{"e164Number": "+15552220123"},
+		Legacy: map[string]interface{}{"e164_number": "string", "extension": "string"},
+	},
+	"google.type.PostalAddress": {
+		JSON: map[string]interface{}{
+			"regionCode":   "US",
+			"postalCode":   "94043",
+			"locality":     "Mountain View",
+			"addressLines": []interface{}{"1600 Amphitheatre Pkwy"},
+		},
+		Legacy: map[string]interface{}{
+			"region_code":   "string",
+			"postal_code":   "string",
+			"locality":      "string",
+			"address_lines": []interface{}{"string"},
+		},
+	},
+}
+
+// wellKnownObjects returns the json examples of the builtin types.
+func (tmpl *Template) wellKnownObjects() map[string]Object {
+	objects := make(map[string]Object, len(wellKnownTypes))
+
+	for name, typ := range wellKnownTypes {
+		if tmpl.LegacyJSON {
+			objects[name] = Object{JSONObject: typ.Legacy}
+		} else {
+			objects[name] = Object{JSONObject: typ.JSON}
+		}
+	}
+
+	return objects
+}
+
+// RegisterType registers the json example of a type which is usually not part of the parsed
+// files, e.g. a common type shared across repos. It overrides builtin well-known types as
+// well as parsed messages and must be called before parsing.
+func (tmpl *Template) RegisterType(fullName string, example interface{}) {
+	if tmpl.Types == nil {
+		tmpl.Types = make(map[string]interface{})
+	}
+
+	tmpl.Types[fullName] = example
+}
+
+// LoadTypes registers the types of a json file mapping full type names to their example,
+// e.g. {"common.v1.Money": {"amount": "1.50", "currency": "USD"}}.
+func (tmpl *Template) LoadTypes(filename string) error {
+	bs, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("read types file failure, path: %s, err: %w", filename, err)
+	}
+
+	var types map[string]interface{}
+	err = json.Unmarshal(bs, &types)
+	if err != nil {
+		return fmt.Errorf("decode types file failure, path: %s, err: %w", filename, err)
+	}
+
+	for name, example := range types {
+		tmpl.RegisterType(name, example)
+	}
+
+	return nil
+}